@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,12 +11,20 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sunriseex/test_wallet/internal/auth"
 	"github.com/sunriseex/test_wallet/internal/config"
 	"github.com/sunriseex/test_wallet/internal/db"
 	"github.com/sunriseex/test_wallet/internal/handler"
 	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/metrics"
 	"github.com/sunriseex/test_wallet/internal/middleware"
+	"github.com/sunriseex/test_wallet/internal/migrations"
+	"github.com/sunriseex/test_wallet/internal/ratelimit"
+	"github.com/sunriseex/test_wallet/internal/rates"
+	"github.com/sunriseex/test_wallet/internal/repository"
 	"github.com/sunriseex/test_wallet/internal/service"
+	"github.com/sunriseex/test_wallet/internal/tracing"
 )
 
 func main() {
@@ -26,19 +35,128 @@ func main() {
 
 	logger.Log.Info("Сервер запускается...")
 
-	database := db.InitDB(cfg)
-	db.InitSchema(database)
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Log.Fatalf("Ошибка настройки трассировки: %v", err)
+	}
+
+	var database *sql.DB
+	var repo repository.WalletRepository
+	var auditRepo repository.AuditRepository
+	var readinessChecks []handler.ReadinessCheck
+
+	switch cfg.StorageDriver {
+	case config.StorageMemory:
+		logger.Log.Warn("Используется хранилище в памяти: данные не сохраняются между перезапусками")
+		memoryRepo := repository.NewMemoryRepository()
+		repo, auditRepo = memoryRepo, memoryRepo
+	default:
+		database = db.InitDB(cfg)
+
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			code := runMigrate(database, os.Args[2:])
+			database.Close()
+			os.Exit(code)
+		}
+
+		migrator, err := migrations.New(database)
+		if err != nil {
+			logger.Log.Fatalf("Ошибка загрузки миграций: %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			logger.Log.Fatalf("Ошибка применения миграций: %v", err)
+		}
+		postgresRepo := repository.NewPostgresRepository(database)
+		repo, auditRepo = postgresRepo, postgresRepo
+		metrics.RegisterDBStats(database)
+		readinessChecks = append(readinessChecks,
+			handler.ReadinessCheck{Name: "database", Check: database.PingContext},
+			handler.ReadinessCheck{Name: "migrations", Check: migrator.Ready},
+		)
+	}
+
+	var rateProvider service.ExchangeRateProvider
+	if cfg.RatesFile != "" {
+		provider, err := rates.LoadFile(cfg.RatesFile)
+		if err != nil {
+			logger.Log.Fatalf("Ошибка загрузки курсов валют: %v", err)
+		}
+		rateProvider = provider
+	} else {
+		logger.Log.Warn("RATES_FILE не задан: обмен валют недоступен")
+	}
 
-	walletService := service.NewWalletService(database)
+	walletService := service.NewWalletService(repo, rateProvider)
+	auditService := service.NewAuditService(auditRepo)
 	workerPool := service.NewWorkerPool(walletService, 50, 1000)
+	metrics.RegisterWorkerQueue(workerPool.QueueDepth)
+	readinessChecks = append(readinessChecks, handler.ReadinessCheck{Name: "worker_pool", Check: workerPool.Ready})
+	go func() {
+		queued, err := workerPool.RequeuePending(context.Background())
+		if err != nil {
+			logger.Log.Errorf("Ошибка восстановления незавершенных операций: %v", err)
+		}
+		if queued > 0 {
+			logger.Log.Infof("Незавершенные операции поставлены в очередь: %d", queued)
+		}
+	}()
+	holdSweeper := service.NewHoldSweeper(walletService, cfg.HoldSweepInterval)
+	holdSweeper.Start()
+	balanceSnapshotter := service.NewBalanceSnapshotter(walletService, cfg.BalanceSnapshotInterval)
+	balanceSnapshotter.Start()
 
 	r := mux.NewRouter()
 
-	walletHandler := handler.NewWalletHandler(logger.Log, walletService)
+	walletHandler := handler.NewWalletHandler(logger.Log, walletService, workerPool)
+	auditHandler := handler.NewAuditHandler(logger.Log, auditService)
+	healthHandler := handler.NewHealthHandler(logger.Log, cfg.ReadinessTimeout, readinessChecks...)
 
+	r.Use(middleware.TracingMiddleware())
+	r.Use(middleware.RequestIDMiddleware)
 	r.Use(middleware.LoggerMiddleware)
-	r.HandleFunc("/api/v1/wallet", walletHandler.CreateOrUpdateWallet).Methods("POST")
-	r.HandleFunc("/api/v1/wallets/{walletId}", walletHandler.GetWalletBalance).Methods("GET")
+	r.Use(middleware.MetricsMiddleware)
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	r.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	r.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
+
+	api := r.PathPrefix("/api/v1").Subrouter()
+	authenticator := newAuthenticator(cfg)
+	switch {
+	case authenticator.Enabled():
+		api.Use(middleware.AuthMiddleware(authenticator))
+	case cfg.AuthDisabled:
+		logger.Log.Warn("AUTH_DISABLED=true: API доступен без аутентификации с правами admin")
+		api.Use(middleware.AnonymousMiddleware)
+	default:
+		logger.Log.Fatal("Ключи аутентификации не заданы: укажите AUTH_API_KEYS_FILE, JWT_HS256_SECRET " +
+			"или JWT_RS256_PUBLIC_KEY_FILE либо явно отключите аутентификацию AUTH_DISABLED=true")
+	}
+	api.Use(middleware.RateLimitMiddleware(ratelimit.NewMemoryStore(), rateLimitRules(cfg)))
+	api.Use(middleware.AuditMiddleware(auditService))
+	api.HandleFunc("/wallet", walletHandler.CreateOrUpdateWallet).Methods("POST")
+	api.HandleFunc("/wallets", walletHandler.CreateWallet).Methods("POST")
+	api.HandleFunc("/wallets", walletHandler.ListWallets).Methods("GET")
+	api.HandleFunc("/wallets/{walletId}", walletHandler.GetWalletBalance).Methods("GET")
+	api.HandleFunc("/wallets/{walletId}", walletHandler.UpdateWallet).Methods("PATCH")
+	api.HandleFunc("/wallets/{walletId}/transactions", walletHandler.GetWalletTransactions).Methods("GET")
+	api.HandleFunc("/wallets/{walletId}/balance", walletHandler.GetBalanceAt).Methods("GET")
+	api.HandleFunc("/wallets/{walletId}/balance/daily", walletHandler.GetDailyBalances).Methods("GET")
+	api.HandleFunc("/wallets/{walletId}/statement", walletHandler.GetStatement).Methods("GET")
+	api.HandleFunc("/wallets/{walletId}/holds", walletHandler.PlaceHold).Methods("POST")
+	api.HandleFunc("/holds/{holdId}", walletHandler.GetHold).Methods("GET")
+	api.HandleFunc("/holds/{holdId}/capture", walletHandler.CaptureHold).Methods("POST")
+	api.HandleFunc("/holds/{holdId}/release", walletHandler.ReleaseHold).Methods("POST")
+	api.HandleFunc("/quotes", walletHandler.CreateQuote).Methods("POST")
+	api.HandleFunc("/conversions", walletHandler.Convert).Methods("POST")
+	api.HandleFunc("/transactions/{transactionId}/reversals", walletHandler.ReverseTransaction).Methods("POST")
+	api.HandleFunc("/operations/{operationId}", walletHandler.GetOperation).Methods("GET")
+	api.HandleFunc("/admin/wallets", walletHandler.SearchWallets).Methods("GET")
+	api.HandleFunc("/admin/ledger/trial-balance", walletHandler.TrialBalance).Methods("GET")
+	api.HandleFunc("/admin/audit", auditHandler.ListAudit).Methods("GET")
+	api.HandleFunc("/admin/audit/verify", auditHandler.VerifyAudit).Methods("GET")
 
 	addr := fmt.Sprintf(":%s", cfg.AppPort)
 
@@ -61,20 +179,81 @@ func main() {
 
 	<-quit
 
-	workerPool.Shutdown()
-
-	database.Close()
-	logger.Log.Info("База данных закрыта успешно")
-
-	logger.Log.Info("Логгер остановлен успешно")
+	healthHandler.SetShuttingDown()
+	logger.Log.Infof("Остановка: ожидание вывода из балансировки %s", cfg.ShutdownDrainDelay)
+	time.Sleep(cfg.ShutdownDrainDelay)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Log.Fatalf("Ошибка при остановке сервера: %v", err)
+		logger.Log.Errorf("Ошибка при остановке сервера: %v", err)
 	}
 
 	logger.Log.Info("Сервер остановлен успешно")
 
+	workerPool.Shutdown()
+	holdSweeper.Stop()
+	balanceSnapshotter.Stop()
+
+	if database != nil {
+		database.Close()
+		logger.Log.Info("База данных закрыта успешно")
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Log.Errorf("Ошибка выгрузки трасс: %v", err)
+	}
+
+	logger.Log.Info("Логгер остановлен успешно")
+
+}
+
+// newAuthenticator собирает аутентификацию из конфигурации. Ошибки в ключах
+// фатальны: сервер не должен подняться открытым из-за опечатки в файле.
+func newAuthenticator(cfg *config.Config) *auth.Authenticator {
+	var keys []auth.APIKey
+	if cfg.AuthAPIKeysFile != "" {
+		loaded, err := auth.LoadAPIKeys(cfg.AuthAPIKeysFile)
+		if err != nil {
+			logger.Log.Fatalf("Ошибка загрузки API-ключей: %v", err)
+		}
+		keys = loaded
+	}
+
+	jwtConfig := auth.JWTConfig{
+		HS256Secret: []byte(cfg.JWTHS256Secret),
+		Issuer:      cfg.JWTIssuer,
+		Audience:    cfg.JWTAudience,
+	}
+	if cfg.JWTRS256PublicKeyFile != "" {
+		key, err := auth.LoadRSAPublicKey(cfg.JWTRS256PublicKeyFile)
+		if err != nil {
+			logger.Log.Fatalf("Ошибка загрузки ключа RS256: %v", err)
+		}
+		jwtConfig.RS256PublicKey = key
+	}
+
+	authenticator, err := auth.NewAuthenticator(keys, jwtConfig)
+	if err != nil {
+		logger.Log.Fatalf("Ошибка настройки аутентификации: %v", err)
+	}
+	return authenticator
+}
+
+// rateLimitRules собирает лимиты по умолчанию из конфигурации и дополняет их
+// лимитами маршрутов из RATE_LIMIT_ROUTES_FILE.
+func rateLimitRules(cfg *config.Config) ratelimit.Rules {
+	defaults := ratelimit.Rule{
+		Client: ratelimit.Limit{Rate: cfg.RateLimitClientRPS, Burst: cfg.RateLimitClientBurst},
+		Wallet: ratelimit.Limit{Rate: cfg.RateLimitWalletRPS, Burst: cfg.RateLimitWalletBurst},
+	}
+	if cfg.RateLimitRoutesFile == "" {
+		return ratelimit.Rules{Default: defaults}
+	}
+	rules, err := ratelimit.LoadRules(cfg.RateLimitRoutesFile, defaults)
+	if err != nil {
+		logger.Log.Fatalf("Ошибка загрузки лимитов запросов: %v", err)
+	}
+	return rules
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/migrations"
+)
+
+const migrateUsage = "usage: server migrate up | down [steps] | status"
+
+// runMigrate выполняет подкоманду `server migrate ...` и возвращает код выхода.
+func runMigrate(database *sql.DB, args []string) int {
+	migrator, err := migrations.New(database)
+	if err != nil {
+		logger.Log.Errorf("Ошибка загрузки миграций: %v", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Log.Errorf("Ошибка применения миграций: %v", err)
+			return 1
+		}
+		logger.Log.Infof("Применено миграций: %d", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			logger.Log.Errorf("Ошибка отката миграций: %v", err)
+			return 1
+		}
+		logger.Log.Infof("Откачено миграций: %d", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Log.Errorf("Ошибка получения статуса миграций: %v", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// Действия журнала аудита. Если обработчик не задал действие, запись
+// получает метод и шаблон маршрута ("POST /api/v1/...").
+const (
+	ActionWalletCreate       = "wallet.create"
+	ActionWalletUpdate       = "wallet.update"
+	ActionDeposit            = "wallet.deposit"
+	ActionWithdraw           = "wallet.withdraw"
+	ActionTransfer           = "wallet.transfer"
+	ActionOperationSubmit    = "operation.submit"
+	ActionHoldPlace          = "hold.place"
+	ActionHoldCapture        = "hold.capture"
+	ActionHoldRelease        = "hold.release"
+	ActionQuoteCreate        = "quote.create"
+	ActionConversionExecute  = "conversion.execute"
+	ActionTransactionReverse = "transaction.reverse"
+)
+
+// Details — то, что обработчик знает о действии: что сделано, с каким
+// кошельком и как изменился баланс. Методы безопасно вызывать на nil, если
+// запрос не аудируется.
+type Details struct {
+	Action        string
+	WalletID      string
+	BalanceBefore *decimal.Decimal
+	BalanceAfter  *decimal.Decimal
+}
+
+type detailsKey struct{}
+
+// WithDetails кладет в контекст пустые Details, которые заполнит обработчик.
+func WithDetails(ctx context.Context) (context.Context, *Details) {
+	d := &Details{}
+	return context.WithValue(ctx, detailsKey{}, d), d
+}
+
+// FromContext возвращает Details запроса или nil.
+func FromContext(ctx context.Context) *Details {
+	d, _ := ctx.Value(detailsKey{}).(*Details)
+	return d
+}
+
+func (d *Details) Set(action, walletID string) {
+	if d == nil {
+		return
+	}
+	d.Action = action
+	d.WalletID = walletID
+}
+
+// SetBalances записывает баланс кошелька до и после действия.
+func (d *Details) SetBalances(before, after decimal.Decimal) {
+	if d == nil {
+		return
+	}
+	d.BalanceBefore = &before
+	d.BalanceAfter = &after
+}
+
+// SetBalanceAfter записывает баланс после действия, когда "до" не было
+// (создание кошелька).
+func (d *Details) SetBalanceAfter(after decimal.Decimal) {
+	if d == nil {
+		return
+	}
+	d.BalanceAfter = &after
+}
+
+// SetTransaction берет балансы из проведенной транзакции кошелька.
+func (d *Details) SetTransaction(t model.Transaction) {
+	d.SetBalances(t.BalanceAfter.Sub(t.Amount), t.BalanceAfter)
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKey — статический ключ клиента. В конфигурации хранится только
+// SHA-256 ключа в hex, сам ключ знает лишь клиент.
+type APIKey struct {
+	Name   string   `json:"name"`
+	Hash   string   `json:"sha256"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// HashAPIKey возвращает SHA-256 ключа в hex — значение для поля sha256.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadAPIKeys читает ключи из JSON-файла вида
+// [{"name": "backoffice", "sha256": "...", "scopes": ["admin"]}].
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+func indexAPIKeys(keys []APIKey) (map[string]APIKey, error) {
+	index := make(map[string]APIKey, len(keys))
+	for _, key := range keys {
+		hash := strings.ToLower(key.Hash)
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key without name")
+		}
+		if raw, err := hex.DecodeString(hash); err != nil || len(raw) != sha256.Size {
+			return nil, fmt.Errorf("API key %s: sha256 must be 64 hex characters", key.Name)
+		}
+		if _, ok := index[hash]; ok {
+			return nil, fmt.Errorf("API key %s: duplicate hash", key.Name)
+		}
+		index[hash] = key
+	}
+	return index, nil
+}
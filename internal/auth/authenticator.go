@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator определяет клиента по заголовку X-API-Key или по
+// Authorization: Bearer <JWT>.
+type Authenticator struct {
+	apiKeys map[string]APIKey
+	jwt     JWTConfig
+	now     func() time.Time
+}
+
+func NewAuthenticator(keys []APIKey, jwt JWTConfig) (*Authenticator, error) {
+	index, err := indexAPIKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &Authenticator{apiKeys: index, jwt: jwt, now: time.Now}, nil
+}
+
+// Enabled сообщает, настроен ли хотя бы один способ аутентификации.
+func (a *Authenticator) Enabled() bool {
+	return len(a.apiKeys) > 0 || len(a.jwt.HS256Secret) > 0 || a.jwt.RS256PublicKey != nil
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		apiKey, ok := a.apiKeys[HashAPIKey(key)]
+		if !ok {
+			return Principal{}, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+		}
+		return Principal{Subject: apiKey.Name, Method: MethodAPIKey, Scopes: apiKey.Scopes}, nil
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	claims, err := a.jwt.verify(strings.TrimSpace(token), a.now())
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return Principal{Subject: claims.Subject, Method: MethodJWT, Scopes: strings.Fields(claims.Scope)}, nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret []byte, claims map[string]any) string {
+	t.Helper()
+	signed := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	signed := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func authenticate(a *Authenticator, header, value string) (Principal, error) {
+	req := httptest.NewRequest("GET", "/api/v1/wallets", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	return a.Authenticate(req)
+}
+
+func TestAuthenticator_APIKey(t *testing.T) {
+	a, err := NewAuthenticator([]APIKey{{Name: "backoffice", Hash: HashAPIKey("s3cret"), Scopes: []string{"admin"}}}, JWTConfig{})
+	require.NoError(t, err)
+	assert.True(t, a.Enabled())
+
+	p, err := authenticate(a, APIKeyHeader, "s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, Principal{Subject: "backoffice", Method: MethodAPIKey, Scopes: []string{"admin"}}, p)
+
+	_, err = authenticate(a, APIKeyHeader, "guess")
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	_, err = authenticate(a, "", "")
+	assert.True(t, errors.Is(err, ErrNoCredentials))
+
+	_, err = NewAuthenticator([]APIKey{{Name: "plain", Hash: "s3cret"}}, JWTConfig{})
+	assert.Error(t, err)
+}
+
+func TestAuthenticator_HS256(t *testing.T) {
+	secret := []byte("hs256-secret")
+	a, err := NewAuthenticator(nil, JWTConfig{HS256Secret: secret, Issuer: "https://id.example.com", Audience: "wallet"})
+	require.NoError(t, err)
+	a.now = func() time.Time { return testNow }
+
+	valid := map[string]any{
+		"sub":   "user-42",
+		"iss":   "https://id.example.com",
+		"aud":   []string{"wallet", "other"},
+		"exp":   testNow.Add(time.Minute).Unix(),
+		"scope": "wallet:read wallet:deposit",
+	}
+	p, err := authenticate(a, "Authorization", "Bearer "+signHS256(t, secret, valid))
+	require.NoError(t, err)
+	assert.Equal(t, Principal{Subject: "user-42", Method: MethodJWT, Scopes: []string{"wallet:read", "wallet:deposit"}}, p)
+
+	invalid := map[string]map[string]any{
+		"expired":      {"sub": "user-42", "iss": "https://id.example.com", "aud": "wallet", "exp": testNow.Add(-time.Hour).Unix()},
+		"no exp":       {"sub": "user-42", "iss": "https://id.example.com", "aud": "wallet"},
+		"wrong issuer": {"sub": "user-42", "iss": "https://evil.example.com", "aud": "wallet", "exp": testNow.Add(time.Minute).Unix()},
+		"wrong aud":    {"sub": "user-42", "iss": "https://id.example.com", "aud": "billing", "exp": testNow.Add(time.Minute).Unix()},
+		"not yet":      {"sub": "user-42", "iss": "https://id.example.com", "aud": "wallet", "exp": testNow.Add(2 * time.Hour).Unix(), "nbf": testNow.Add(time.Hour).Unix()},
+	}
+	for name, claims := range invalid {
+		_, err := authenticate(a, "Authorization", "Bearer "+signHS256(t, secret, claims))
+		assert.True(t, errors.Is(err, ErrInvalidCredentials), name)
+	}
+
+	_, err = authenticate(a, "Authorization", "Bearer "+signHS256(t, []byte("other"), valid))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+
+	unsigned := encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, valid) + "."
+	_, err = authenticate(a, "Authorization", "Bearer "+unsigned)
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
+
+func TestAuthenticator_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	a, err := NewAuthenticator(nil, JWTConfig{RS256PublicKey: &key.PublicKey})
+	require.NoError(t, err)
+	a.now = func() time.Time { return testNow }
+
+	claims := map[string]any{"sub": "user-7", "exp": testNow.Add(time.Minute).Unix()}
+	p, err := authenticate(a, "Authorization", "Bearer "+signRS256(t, key, claims))
+	require.NoError(t, err)
+	assert.Equal(t, "user-7", p.Subject)
+
+	// HS256-токен не принимается, если настроен только RS256.
+	_, err = authenticate(a, "Authorization", "Bearer "+signHS256(t, []byte("anything"), claims))
+	assert.True(t, errors.Is(err, ErrInvalidCredentials))
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtLeeway — допуск на расхождение часов при проверке exp и nbf.
+const jwtLeeway = 30 * time.Second
+
+var errInvalidToken = errors.New("invalid token")
+
+// JWTConfig — локально настроенные ключи и ожидаемые claims. Токен
+// принимается только с тем алгоритмом, для которого задан ключ.
+type JWTConfig struct {
+	HS256Secret    []byte
+	RS256PublicKey *rsa.PublicKey
+	// Issuer и Audience проверяются, если заданы.
+	Issuer   string
+	Audience string
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Scope     string   `json:"scope"`
+}
+
+// audience — claim aud, который по RFC 7519 бывает строкой или массивом.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// LoadRSAPublicKey читает открытый ключ RS256 из PEM-файла (PKIX или PKCS#1).
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA public key", path)
+	}
+	return key, nil
+}
+
+// verify проверяет подпись и срок действия токена и возвращает его claims.
+func (c JWTConfig) verify(token string, now time.Time) (jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return jwtClaims{}, errInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return jwtClaims{}, errInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return jwtClaims{}, errInvalidToken
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch header.Alg {
+	case "HS256":
+		if len(c.HS256Secret) == 0 {
+			return jwtClaims{}, fmt.Errorf("%w: HS256 is not configured", errInvalidToken)
+		}
+		mac := hmac.New(sha256.New, c.HS256Secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return jwtClaims{}, fmt.Errorf("%w: bad signature", errInvalidToken)
+		}
+	case "RS256":
+		if c.RS256PublicKey == nil {
+			return jwtClaims{}, fmt.Errorf("%w: RS256 is not configured", errInvalidToken)
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(c.RS256PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return jwtClaims{}, fmt.Errorf("%w: bad signature", errInvalidToken)
+		}
+	default:
+		return jwtClaims{}, fmt.Errorf("%w: unsupported alg %q", errInvalidToken, header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return jwtClaims{}, errInvalidToken
+	}
+	switch {
+	case claims.Subject == "":
+		return jwtClaims{}, fmt.Errorf("%w: missing sub", errInvalidToken)
+	case claims.ExpiresAt == nil:
+		return jwtClaims{}, fmt.Errorf("%w: missing exp", errInvalidToken)
+	case now.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)):
+		return jwtClaims{}, fmt.Errorf("%w: expired", errInvalidToken)
+	case claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)):
+		return jwtClaims{}, fmt.Errorf("%w: not valid yet", errInvalidToken)
+	case c.Issuer != "" && claims.Issuer != c.Issuer:
+		return jwtClaims{}, fmt.Errorf("%w: unexpected iss", errInvalidToken)
+	case c.Audience != "" && !claims.Audience.contains(c.Audience):
+		return jwtClaims{}, fmt.Errorf("%w: unexpected aud", errInvalidToken)
+	}
+	return claims, nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,34 @@
+package auth
+
+import "context"
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	// MethodNone — аутентификация отключена (AUTH_DISABLED).
+	MethodNone = "none"
+)
+
+// Principal — аутентифицированный клиент запроса. Для API-ключа Subject —
+// имя ключа из конфигурации, для JWT — claim sub.
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"`
+	Scopes  []string `json:"scopes,omitempty"`
+}
+
+// Anonymous — клиент всех запросов при отключенной аутентификации. У него
+// права admin: отключение аутентификации — явное решение для локального запуска.
+var Anonymous = Principal{Subject: "anonymous", Method: MethodNone, Scopes: []string{ScopeAdmin}}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает клиента, которого аутентифицировал Middleware.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
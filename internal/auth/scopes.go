@@ -0,0 +1,28 @@
+package auth
+
+const (
+	ScopeWalletRead     = "wallet:read"
+	ScopeWalletDeposit  = "wallet:deposit"
+	ScopeWalletWithdraw = "wallet:withdraw"
+	// ScopeWalletManage — создание кошельков и изменение статуса и метаданных.
+	ScopeWalletManage = "wallet:manage"
+	// ScopeAdmin дает все остальные права и снимает проверку владельца.
+	ScopeAdmin = "admin"
+)
+
+// HasScope сообщает, разрешено ли клиенту действие со scope.
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// EndUser сообщает, что запрос пришел с токеном конечного пользователя:
+// такой клиент работает только со своими кошельками (OwnerID == Subject).
+// API-ключи выдаются доверенным сервисам и владельца не проверяют.
+func (p Principal) EndUser() bool {
+	return p.Method == MethodJWT && !p.HasScope(ScopeAdmin)
+}
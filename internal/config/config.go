@@ -2,18 +2,51 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sunriseex/test_wallet/internal/logger"
 )
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type Config struct {
-	AppPort string
-	DBHost  string
-	DBPort  string
-	DBUser  string
-	DBPass  string
-	DBName  string
+	AppPort            string
+	ReadinessTimeout   time.Duration
+	ShutdownDrainDelay time.Duration
+	HoldSweepInterval  time.Duration
+	// Как часто сохранять снимки балансов на конец прошедших дней.
+	BalanceSnapshotInterval time.Duration
+	StorageDriver           string
+	RatesFile               string
+	// Без хотя бы одного источника ключей сервер не стартует, если явно не
+	// задан AuthDisabled.
+	AuthDisabled          bool
+	AuthAPIKeysFile       string
+	JWTHS256Secret        string
+	JWTRS256PublicKeyFile string
+	JWTIssuer             string
+	JWTAudience           string
+	// Лимиты запросов по умолчанию (в секунду, 0 — без ограничения) и файл
+	// с лимитами отдельных маршрутов.
+	RateLimitClientRPS   float64
+	RateLimitClientBurst int
+	RateLimitWalletRPS   float64
+	RateLimitWalletBurst int
+	RateLimitRoutesFile  string
+	// Экспортер трассировки: none, stdout или otlp, и доля трассируемых
+	// запросов без входящего traceparent.
+	TracingExporter    string
+	TracingSampleRatio float64
+	DBHost             string
+	DBPort             string
+	DBUser             string
+	DBPass             string
+	DBName             string
 }
 
 func LoadConfig() *Config {
@@ -22,11 +55,89 @@ func LoadConfig() *Config {
 		logger.Log.Error("Error loading config no .env file")
 	}
 	return &Config{
-		AppPort: os.Getenv("APP_PORT"),
-		DBHost:  os.Getenv("DB_HOST"),
-		DBPort:  os.Getenv("DB_PORT"),
-		DBUser:  os.Getenv("DB_USER"),
-		DBPass:  os.Getenv("DB_PASS"),
-		DBName:  os.Getenv("DB_NAME"),
+		AppPort:                 os.Getenv("APP_PORT"),
+		ReadinessTimeout:        getDuration("READINESS_TIMEOUT", time.Second),
+		ShutdownDrainDelay:      getDuration("SHUTDOWN_DRAIN_DELAY", 0),
+		HoldSweepInterval:       getDuration("HOLD_SWEEP_INTERVAL", 30*time.Second),
+		BalanceSnapshotInterval: getDuration("BALANCE_SNAPSHOT_INTERVAL", time.Hour),
+		StorageDriver:           getEnv("STORAGE_DRIVER", StoragePostgres),
+		RatesFile:               os.Getenv("RATES_FILE"),
+		AuthDisabled:            getBool("AUTH_DISABLED", false),
+		AuthAPIKeysFile:         os.Getenv("AUTH_API_KEYS_FILE"),
+		JWTHS256Secret:          os.Getenv("JWT_HS256_SECRET"),
+		JWTRS256PublicKeyFile:   os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"),
+		JWTIssuer:               os.Getenv("JWT_ISSUER"),
+		JWTAudience:             os.Getenv("JWT_AUDIENCE"),
+		RateLimitClientRPS:      getFloat("RATE_LIMIT_CLIENT_RPS", 0),
+		RateLimitClientBurst:    getInt("RATE_LIMIT_CLIENT_BURST", 1),
+		RateLimitWalletRPS:      getFloat("RATE_LIMIT_WALLET_RPS", 0),
+		RateLimitWalletBurst:    getInt("RATE_LIMIT_WALLET_BURST", 1),
+		RateLimitRoutesFile:     os.Getenv("RATE_LIMIT_ROUTES_FILE"),
+		TracingExporter:         getEnv("TRACING_EXPORTER", "none"),
+		TracingSampleRatio:      getFloat("TRACING_SAMPLE_RATIO", 1),
+		DBHost:                  os.Getenv("DB_HOST"),
+		DBPort:                  os.Getenv("DB_PORT"),
+		DBUser:                  os.Getenv("DB_USER"),
+		DBPass:                  os.Getenv("DB_PASS"),
+		DBName:                  os.Getenv("DB_NAME"),
+	}
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Log.Errorf("Invalid duration in %s: %v, using %s", key, err, def)
+		return def
+	}
+	return d
+}
+
+func getFloat(key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		logger.Log.Errorf("Invalid number in %s: %q, using %v", key, value, def)
+		return def
+	}
+	return f
+}
+
+func getInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger.Log.Errorf("Invalid integer in %s: %q, using %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+func getBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Log.Errorf("Invalid boolean in %s: %q, using %t", key, value, def)
+		return def
+	}
+	return b
+}
+
+func getEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return def
 }
@@ -0,0 +1,46 @@
+package currency
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Default — валюта кошельков, для которых она не указана при создании.
+const Default = "RUB"
+
+// scales — число знаков после запятой для поддерживаемых валют.
+var scales = map[string]int32{
+	"RUB": 2,
+	"USD": 2,
+	"EUR": 2,
+	"KZT": 2,
+	"CNY": 2,
+	"JPY": 0,
+	"BTC": 8,
+}
+
+// Normalize приводит код валюты к верхнему регистру.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func Supported(code string) bool {
+	_, ok := scales[code]
+	return ok
+}
+
+func Scale(code string) (int32, bool) {
+	scale, ok := scales[code]
+	return scale, ok
+}
+
+// ValidAmount сообщает, укладывается ли amount в точность валюты code.
+// Незначащие нули допускаются: 10.500 для RUB корректна.
+func ValidAmount(code string, amount decimal.Decimal) bool {
+	scale, ok := scales[code]
+	if !ok {
+		return false
+	}
+	return amount.Equal(amount.Truncate(scale))
+}
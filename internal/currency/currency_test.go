@@ -0,0 +1,36 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidAmount(t *testing.T) {
+	testCases := []struct {
+		code   string
+		amount string
+		valid  bool
+	}{
+		{"RUB", "10.25", true},
+		{"RUB", "10.500", true},
+		{"RUB", "10.255", false},
+		{"JPY", "100", true},
+		{"JPY", "100.5", false},
+		{"BTC", "0.00000001", true},
+		{"BTC", "0.000000001", false},
+		{"XXX", "1", false},
+	}
+
+	for _, tc := range testCases {
+		amount := decimal.RequireFromString(tc.amount)
+		assert.Equal(t, tc.valid, ValidAmount(tc.code, amount), "%s %s", tc.code, tc.amount)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "USD", Normalize(" usd "))
+	assert.True(t, Supported(Normalize("jpy")))
+	assert.False(t, Supported(Normalize("usdt")))
+}
@@ -5,14 +5,21 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/sunriseex/test_wallet/internal/config"
 	"github.com/sunriseex/test_wallet/internal/logger"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 func InitDB(cfg *config.Config) *sql.DB {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPass, cfg.DBName)
-	db, err := sql.Open("pgx", connStr)
+	// otelsql открывает спан на каждый запрос, begin и commit; сброс сессии и
+	// чтение строк не трассируются, чтобы не раздувать трассы.
+	db, err := otelsql.Open("pgx", connStr,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
 		logger.Log.Fatalf("Error connect to DB: %v", err)
 	}
@@ -29,30 +36,3 @@ func InitDB(cfg *config.Config) *sql.DB {
 	return db
 
 }
-
-func InitSchema(db *sql.DB) {
-	query := `
-	CREATE TABLE IF NOT EXISTS wallet_db (
-		wallet_id UUID PRIMARY KEY,
-		balance NUMERIC NOT NULL DEFAULT 0,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-	);`
-
-	_, err := db.Exec(query)
-	if err != nil {
-		logger.Log.Fatalf("Error creating table: %v", err)
-	}
-	logger.Log.Info("Successfully created wallet_db table")
-
-	indexQuery := `
-	CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS wallet_id_idx 
-	ON wallet_db (wallet_id)`
-
-	_, err = db.Exec(indexQuery)
-	if err != nil {
-		logger.Log.Warnf("Index creation warning: %v", err)
-	}
-	logger.Log.Info("Successfully created wallet_id_idx index")
-
-}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+// SearchWallets — список кошельков для бэк-офиса с фильтрами, сортировкой
+// (sort=createdAt|updatedAt|balance, order=asc|desc) и курсорной пагинацией.
+func (h *WalletHandler) SearchWallets(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, auth.ScopeAdmin) {
+		return
+	}
+
+	query, err := parseWalletQuery(r)
+	if err != nil {
+		h.log(r).WithError(err).Error("Неверные параметры списка кошельков")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	page, err := h.WalletService.ListWallets(r.Context(), query)
+	if err != nil {
+		h.log(r).WithError(err).Error("Ошибка получения списка кошельков")
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, page)
+}
+
+// TrialBalance — оборотно-сальдовая ведомость главной книги: балансы счетов
+// по видам и валютам, равенство дебета и кредита и сверка с кошельками.
+func (h *WalletHandler) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, auth.ScopeAdmin) {
+		return
+	}
+
+	result, err := h.WalletService.TrialBalance(r.Context())
+	if err != nil {
+		h.log(r).WithError(err).Error("Ошибка сведения книги")
+		writeServiceError(w, err)
+		return
+	}
+	if !result.Balanced || !result.Reconciled {
+		h.log(r).Errorf("Книга не сходится: balanced=%t, reconciled=%t", result.Balanced, result.Reconciled)
+	}
+	writeJSON(w, result)
+}
+
+func parseWalletQuery(r *http.Request) (service.WalletQuery, error) {
+	values := r.URL.Query()
+	query := service.WalletQuery{
+		OwnerID: values.Get("ownerId"),
+		Status:  values.Get("status"),
+		SortBy:  values.Get("sort"),
+		Cursor:  values.Get("cursor"),
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return service.WalletQuery{}, fmt.Errorf("Неверный параметр order")
+	}
+
+	var err error
+	if query.Limit, err = queryInt(r, "limit", service.DefaultWalletsLimit); err != nil {
+		return service.WalletQuery{}, fmt.Errorf("Неверный параметр limit")
+	}
+
+	for name, dst := range map[string]**decimal.Decimal{
+		"minBalance": &query.MinBalance,
+		"maxBalance": &query.MaxBalance,
+	} {
+		if value := values.Get(name); value != "" {
+			amount, err := decimal.NewFromString(value)
+			if err != nil {
+				return service.WalletQuery{}, fmt.Errorf("Неверный параметр %s", name)
+			}
+			*dst = &amount
+		}
+	}
+
+	for name, dst := range map[string]*time.Time{
+		"createdFrom": &query.CreatedFrom,
+		"createdTo":   &query.CreatedTo,
+		"updatedFrom": &query.UpdatedFrom,
+		"updatedTo":   &query.UpdatedTo,
+	} {
+		if value := values.Get(name); value != "" {
+			if *dst, err = time.Parse(time.RFC3339, value); err != nil {
+				return service.WalletQuery{}, fmt.Errorf("Неверный параметр %s: ожидается RFC3339", name)
+			}
+		}
+	}
+	return query, nil
+}
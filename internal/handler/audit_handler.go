@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+// AuditHandler отдает журнал аудита бэк-офису. Доступ — только со scope admin.
+type AuditHandler struct {
+	Logger *logrus.Logger
+	Audit  service.AuditService
+}
+
+func NewAuditHandler(logger *logrus.Logger, audit service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		Logger: logger,
+		Audit:  audit,
+	}
+}
+
+// ListAudit — записи журнала по возрастанию ID с фильтрами walletId, actor,
+// action, from/to (RFC3339) и курсорной пагинацией.
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	query, err := parseAuditQuery(r)
+	if err != nil {
+		h.log(r).WithError(err).Error("Неверные параметры журнала аудита")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	page, err := h.Audit.ListAudit(r.Context(), query)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, page)
+}
+
+// VerifyAudit пересчитывает цепочку хешей и сообщает первую испорченную запись.
+func (h *AuditHandler) VerifyAudit(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	result, err := h.Audit.VerifyAudit(r.Context())
+	if err != nil {
+		h.log(r).WithError(err).Error("Ошибка проверки журнала аудита")
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func (h *AuditHandler) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		unauthorized(w, r, h.log(r))
+		return false
+	}
+	if principal.HasScope(auth.ScopeAdmin) {
+		return true
+	}
+	h.log(r).Warnf("Доступ запрещен: subject=%s, method=%s: missing scope %s", principal.Subject, principal.Method, auth.ScopeAdmin)
+	writeError(w, http.StatusForbidden, codeForbidden, "missing scope "+auth.ScopeAdmin)
+	return false
+}
+
+func (h *AuditHandler) log(r *http.Request) *logrus.Entry {
+	return h.Logger.WithFields(logger.Fields(r.Context()))
+}
+
+func parseAuditQuery(r *http.Request) (service.AuditQuery, error) {
+	values := r.URL.Query()
+	query := service.AuditQuery{
+		WalletID: values.Get("walletId"),
+		Actor:    values.Get("actor"),
+		Action:   values.Get("action"),
+		Cursor:   values.Get("cursor"),
+	}
+
+	var err error
+	if query.Limit, err = queryInt(r, "limit", service.DefaultAuditLimit); err != nil {
+		return service.AuditQuery{}, fmt.Errorf("Неверный параметр limit")
+	}
+	for name, dst := range map[string]*time.Time{
+		"from": &query.From,
+		"to":   &query.To,
+	} {
+		if value := values.Get(name); value != "" {
+			if *dst, err = time.Parse(time.RFC3339, value); err != nil {
+				return service.AuditQuery{}, fmt.Errorf("Неверный параметр %s: ожидается RFC3339", name)
+			}
+		}
+	}
+	return query, nil
+}
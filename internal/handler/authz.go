@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sunriseex/test_wallet/internal/audit"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+const (
+	codeForbidden    = "FORBIDDEN"
+	codeUnauthorized = "UNAUTHORIZED"
+)
+
+// operationScopes — права, необходимые для операций POST /api/v1/wallet.
+var operationScopes = map[string]string{
+	model.OperationDeposit:  auth.ScopeWalletDeposit,
+	model.OperationWithdraw: auth.ScopeWalletWithdraw,
+	model.OperationTransfer: auth.ScopeWalletWithdraw,
+}
+
+// operationActions — действия журнала аудита для операций POST /api/v1/wallet.
+var operationActions = map[string]string{
+	model.OperationDeposit:  audit.ActionDeposit,
+	model.OperationWithdraw: audit.ActionWithdraw,
+	model.OperationTransfer: audit.ActionTransfer,
+}
+
+// authorize проверяет, что у клиента есть scope, а токен конечного
+// пользователя владеет каждым из walletIDs. При отказе отвечает 403 и
+// возвращает false. Запрос без клиента в контексте (мимо AuthMiddleware)
+// получает 401.
+func (h *WalletHandler) authorize(w http.ResponseWriter, r *http.Request, scope string, walletIDs ...string) bool {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		unauthorized(w, r, h.log(r))
+		return false
+	}
+	if !principal.HasScope(scope) {
+		h.forbid(w, r, principal, "missing scope "+scope)
+		return false
+	}
+	if !principal.EndUser() {
+		return true
+	}
+
+	for _, walletID := range walletIDs {
+		wallet, err := h.WalletService.GetBalance(r.Context(), walletID)
+		if errors.Is(err, service.ErrWalletNotFound) || (err == nil && wallet.OwnerID != principal.Subject) {
+			h.forbid(w, r, principal, "wallet belongs to another owner")
+			return false
+		}
+		if err != nil {
+			writeServiceError(w, err)
+			return false
+		}
+	}
+	return true
+}
+
+// authorizeHold проверяет права на холд через кошелек, к которому он относится.
+func (h *WalletHandler) authorizeHold(w http.ResponseWriter, r *http.Request, scope, holdID string) bool {
+	if _, ok := auth.FromContext(r.Context()); !ok {
+		unauthorized(w, r, h.log(r))
+		return false
+	}
+	hold, err := h.WalletService.GetHold(r.Context(), holdID)
+	if err != nil {
+		writeServiceError(w, err)
+		return false
+	}
+	return h.authorize(w, r, scope, hold.WalletID)
+}
+
+// clientIdempotencyKey привязывает ключ идемпотентности к клиенту: одинаковые
+// ключи разных клиентов не конфликтуют и не возвращают чужой результат.
+func clientIdempotencyKey(r *http.Request, key string) string {
+	principal, ok := auth.FromContext(r.Context())
+	if key == "" || !ok {
+		return key
+	}
+	return principal.Method + ":" + principal.Subject + ":" + key
+}
+
+// endUser возвращает клиента, если запрос пришел с токеном конечного пользователя.
+func endUser(r *http.Request) (auth.Principal, bool) {
+	principal, ok := auth.FromContext(r.Context())
+	return principal, ok && principal.EndUser()
+}
+
+func (h *WalletHandler) forbid(w http.ResponseWriter, r *http.Request, principal auth.Principal, reason string) {
+	h.log(r).Warnf("Доступ запрещен: subject=%s, method=%s: %s", principal.Subject, principal.Method, reason)
+	writeError(w, http.StatusForbidden, codeForbidden, reason)
+}
+
+// unauthorized отвечает 401 на запрос без аутентифицированного клиента.
+func unauthorized(w http.ResponseWriter, r *http.Request, log *logrus.Entry) {
+	log.Warnf("Запрос без аутентификации: [%s] %s", r.Method, r.URL.Path)
+	w.Header().Set("WWW-Authenticate", `Bearer realm="wallet"`)
+	writeError(w, http.StatusUnauthorized, codeUnauthorized, "authentication required")
+}
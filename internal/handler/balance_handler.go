@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+// GetBalanceAt отдает баланс кошелька на момент at (RFC 3339). Без at
+// отдается текущий баланс.
+func (h *WalletHandler) GetBalanceAt(w http.ResponseWriter, r *http.Request) {
+	walletID := mux.Vars(r)["walletId"]
+	if _, err := uuid.Parse(walletID); err != nil {
+		h.log(r).WithError(err).Errorf("Invalid wallet ID: %s", walletID)
+		writeServiceError(w, service.ErrInvalidWalletID)
+		return
+	}
+
+	at := time.Now().UTC()
+	if value := r.URL.Query().Get("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр at")
+			return
+		}
+		at = parsed
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletRead, walletID) {
+		return
+	}
+
+	balance, err := h.WalletService.BalanceAt(r.Context(), walletID, at)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("BalanceAt error: %s", walletID)
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(balance); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+	}
+}
+
+// GetDailyBalances отдает баланс кошелька на конец каждого дня (UTC) из
+// [from, to]. Даты в формате YYYY-MM-DD, to по умолчанию — сегодня.
+func (h *WalletHandler) GetDailyBalances(w http.ResponseWriter, r *http.Request) {
+	walletID := mux.Vars(r)["walletId"]
+	if _, err := uuid.Parse(walletID); err != nil {
+		h.log(r).WithError(err).Errorf("Invalid wallet ID: %s", walletID)
+		writeServiceError(w, service.ErrInvalidWalletID)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := time.Parse(model.DateLayout, query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр from")
+		return
+	}
+	to := time.Now().UTC()
+	if value := query.Get("to"); value != "" {
+		if to, err = time.Parse(model.DateLayout, value); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр to")
+			return
+		}
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletRead, walletID) {
+		return
+	}
+
+	series, err := h.WalletService.DailyBalances(r.Context(), walletID, from, to)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("DailyBalances error: %s", walletID)
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(series); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/audit"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+type QuoteRequest struct {
+	FromCurrency string `json:"fromCurrency"`
+	ToCurrency   string `json:"toCurrency"`
+	// TTLSeconds — сколько секунд курс остается зафиксированным.
+	TTLSeconds int64 `json:"ttlSeconds,omitempty"`
+}
+
+type ConversionRequest struct {
+	FromWalletID string          `json:"fromWalletId"`
+	ToWalletID   string          `json:"toWalletId"`
+	Amount       decimal.Decimal `json:"amount"`
+	QuoteID      string          `json:"quoteId,omitempty"`
+}
+
+func (h *WalletHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	audit.FromContext(r.Context()).Set(audit.ActionQuoteCreate, "")
+
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletRead) {
+		return
+	}
+
+	quote, err := h.WalletService.CreateQuote(r.Context(), req.FromCurrency, req.ToCurrency, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка получения курса: %s/%s", req.FromCurrency, req.ToCurrency)
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(quote); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+	}
+}
+
+func (h *WalletHandler) Convert(w http.ResponseWriter, r *http.Request) {
+	var req ConversionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	r = r.WithContext(logger.WithWalletID(r.Context(), req.FromWalletID))
+	audit.FromContext(r.Context()).Set(audit.ActionConversionExecute, req.FromWalletID)
+
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Слишком длинный ключ идемпотентности")
+		return
+	}
+	if !h.authorize(w, r, auth.ScopeWalletWithdraw, req.FromWalletID, req.ToWalletID) {
+		return
+	}
+	ctx := service.WithIdempotencyKey(r.Context(), clientIdempotencyKey(r, idempotencyKey))
+
+	conversion, err := h.WalletService.Convert(ctx, req.FromWalletID, req.ToWalletID, req.Amount, req.QuoteID)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка обмена: From=%s, To=%s, Amount=%s", req.FromWalletID, req.ToWalletID, req.Amount)
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, conversion)
+}
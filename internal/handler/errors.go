@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+const codeInvalidRequest = "INVALID_REQUEST"
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+var errorStatuses = map[*service.Error]int{
+	service.ErrInvalidWalletID:        http.StatusUnprocessableEntity,
+	service.ErrInvalidAmount:          http.StatusUnprocessableEntity,
+	service.ErrSameWallet:             http.StatusUnprocessableEntity,
+	service.ErrInvalidOperationType:   http.StatusUnprocessableEntity,
+	service.ErrInvalidCurrency:        http.StatusUnprocessableEntity,
+	service.ErrCurrencyMismatch:       http.StatusUnprocessableEntity,
+	service.ErrInvalidAmountScale:     http.StatusUnprocessableEntity,
+	service.ErrInvalidWalletStatus:    http.StatusUnprocessableEntity,
+	service.ErrInvalidOwnerID:         http.StatusUnprocessableEntity,
+	service.ErrInvalidMetadata:        http.StatusUnprocessableEntity,
+	service.ErrInvalidLabels:          http.StatusUnprocessableEntity,
+	service.ErrInvalidSort:            http.StatusUnprocessableEntity,
+	service.ErrInvalidLimit:           http.StatusUnprocessableEntity,
+	service.ErrInvalidCursor:          http.StatusUnprocessableEntity,
+	service.ErrInvalidBalanceTime:     http.StatusUnprocessableEntity,
+	service.ErrBalanceBeforeCreation:  http.StatusUnprocessableEntity,
+	service.ErrInvalidDateRange:       http.StatusUnprocessableEntity,
+	service.ErrSameCurrency:           http.StatusUnprocessableEntity,
+	service.ErrRateNotAvailable:       http.StatusUnprocessableEntity,
+	service.ErrInvalidQuoteTTL:        http.StatusUnprocessableEntity,
+	service.ErrInvalidHoldTTL:         http.StatusUnprocessableEntity,
+	service.ErrCaptureExceedsHold:     http.StatusUnprocessableEntity,
+	service.ErrNotReversible:          http.StatusUnprocessableEntity,
+	service.ErrReversalExceedsAmount:  http.StatusUnprocessableEntity,
+	service.ErrInsufficientFunds:      http.StatusPaymentRequired,
+	service.ErrWalletNotFound:         http.StatusNotFound,
+	service.ErrOperationNotFound:      http.StatusNotFound,
+	service.ErrHoldNotFound:           http.StatusNotFound,
+	service.ErrQuoteNotFound:          http.StatusNotFound,
+	service.ErrTransactionNotFound:    http.StatusNotFound,
+	service.ErrHoldNotActive:          http.StatusConflict,
+	service.ErrHoldExpired:            http.StatusConflict,
+	service.ErrQuoteExpired:           http.StatusConflict,
+	service.ErrAlreadyReversed:        http.StatusConflict,
+	service.ErrIdempotencyKeyConflict: http.StatusConflict,
+	service.ErrWalletExists:           http.StatusConflict,
+	service.ErrWalletFrozen:           http.StatusConflict,
+	service.ErrWalletClosed:           http.StatusConflict,
+	service.ErrWalletNotEmpty:         http.StatusConflict,
+	service.ErrQueueFull:              http.StatusTooManyRequests,
+	service.ErrRetriesExhausted:       http.StatusServiceUnavailable,
+	service.ErrStorageUnavailable:     http.StatusServiceUnavailable,
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// writeServiceError отдает ошибку сервиса в формате ErrorResponse. Доменные
+// ошибки получают свой код и HTTP-статус, все остальное — 500 без деталей.
+func writeServiceError(w http.ResponseWriter, err error) {
+	var svcErr *service.Error
+	if !errors.As(err, &svcErr) {
+		writeError(w, http.StatusInternalServerError, service.ErrCodeInternal, "Ошибка сервера")
+		return
+	}
+
+	status, ok := errorStatuses[svcErr]
+	if !ok {
+		status = http.StatusBadRequest
+	}
+	writeError(w, status, svcErr.Code, svcErr.Message)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type HealthHandler struct {
+	Logger       *logrus.Logger
+	Timeout      time.Duration
+	Checks       []ReadinessCheck
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(
+	logger *logrus.Logger,
+	timeout time.Duration,
+	checks ...ReadinessCheck,
+) *HealthHandler {
+	return &HealthHandler{
+		Logger:  logger,
+		Timeout: timeout,
+		Checks:  checks,
+	}
+}
+
+// SetShuttingDown переводит /readyz в состояние 503, чтобы балансировщик
+// перестал отправлять трафик до остановки сервера.
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.shuttingDown.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, HealthResponse{Status: "shutting_down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+	defer cancel()
+
+	resp := HealthResponse{Status: "ok", Checks: make(map[string]string, len(h.Checks))}
+	status := http.StatusOK
+	for _, check := range h.Checks {
+		if err := check.Check(ctx); err != nil {
+			h.Logger.WithError(err).Warnf("Readiness check failed: %s", check.Name)
+			resp.Checks[check.Name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[check.Name] = "ok"
+	}
+
+	writeHealth(w, status, resp)
+}
+
+func writeHealth(w http.ResponseWriter, status int, resp HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLiveness(t *testing.T) {
+	h := NewHealthHandler(logrus.New(), time.Second)
+
+	w := httptest.NewRecorder()
+	h.Liveness(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	ok := ReadinessCheck{Name: "ok", Check: func(ctx context.Context) error { return nil }}
+	failing := ReadinessCheck{Name: "database", Check: func(ctx context.Context) error { return errors.New("connection refused") }}
+	slow := ReadinessCheck{Name: "slow", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	testCases := []struct {
+		name           string
+		checks         []ReadinessCheck
+		shuttingDown   bool
+		expectedStatus int
+	}{
+		{name: "Все проверки успешны", checks: []ReadinessCheck{ok}, expectedStatus: http.StatusOK},
+		{name: "БД недоступна", checks: []ReadinessCheck{ok, failing}, expectedStatus: http.StatusServiceUnavailable},
+		{name: "Превышен таймаут", checks: []ReadinessCheck{slow}, expectedStatus: http.StatusServiceUnavailable},
+		{name: "Идет остановка", checks: []ReadinessCheck{ok}, shuttingDown: true, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHealthHandler(logrus.New(), 10*time.Millisecond, tc.checks...)
+			if tc.shuttingDown {
+				h.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			h.Readiness(w, httptest.NewRequest("GET", "/readyz", nil))
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("%s: Expected %d, got %d", tc.name, tc.expectedStatus, w.Code)
+			}
+		})
+	}
+}
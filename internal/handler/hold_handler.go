@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/audit"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+type HoldRequest struct {
+	Amount     decimal.Decimal `json:"amount"`
+	TTLSeconds int64           `json:"ttlSeconds"`
+}
+
+type CaptureRequest struct {
+	// Amount — сумма списания; если не задана, списывается весь холд.
+	Amount decimal.Decimal `json:"amount"`
+}
+
+func (h *WalletHandler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	walletID := mux.Vars(r)["walletId"]
+	audit.FromContext(r.Context()).Set(audit.ActionHoldPlace, walletID)
+	if _, err := uuid.Parse(walletID); err != nil {
+		h.log(r).WithError(err).Errorf("Invalid wallet ID: %s", walletID)
+		writeServiceError(w, service.ErrInvalidWalletID)
+		return
+	}
+
+	var req HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletWithdraw, walletID) {
+		return
+	}
+
+	hold, err := h.WalletService.PlaceHold(r.Context(), walletID, req.Amount, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка резервирования: WalletID=%s, Amount=%s", walletID, req.Amount)
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/holds/"+hold.ID)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(hold); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+	}
+}
+
+func (h *WalletHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	holdID := mux.Vars(r)["holdId"]
+
+	hold, err := h.WalletService.GetHold(r.Context(), holdID)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("GetHold error: %s", holdID)
+		writeServiceError(w, err)
+		return
+	}
+	if !h.authorize(w, r, auth.ScopeWalletRead, hold.WalletID) {
+		return
+	}
+	writeJSON(w, hold)
+}
+
+func (h *WalletHandler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	holdID := mux.Vars(r)["holdId"]
+	details := audit.FromContext(r.Context())
+	details.Set(audit.ActionHoldCapture, "")
+
+	var req CaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Слишком длинный ключ идемпотентности")
+		return
+	}
+	if !h.authorizeHold(w, r, auth.ScopeWalletWithdraw, holdID) {
+		return
+	}
+	ctx := service.WithIdempotencyKey(r.Context(), clientIdempotencyKey(r, idempotencyKey))
+
+	transaction, err := h.WalletService.CaptureHold(ctx, holdID, req.Amount)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка списания холда: HoldID=%s, Amount=%s", holdID, req.Amount)
+		writeServiceError(w, err)
+		return
+	}
+	details.Set(audit.ActionHoldCapture, transaction.WalletID)
+	details.SetTransaction(transaction)
+	writeJSON(w, transaction)
+}
+
+func (h *WalletHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	holdID := mux.Vars(r)["holdId"]
+	details := audit.FromContext(r.Context())
+	details.Set(audit.ActionHoldRelease, "")
+	if !h.authorizeHold(w, r, auth.ScopeWalletWithdraw, holdID) {
+		return
+	}
+
+	hold, err := h.WalletService.ReleaseHold(r.Context(), holdID)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка снятия холда: HoldID=%s", holdID)
+		writeServiceError(w, err)
+		return
+	}
+	details.Set(audit.ActionHoldRelease, hold.WalletID)
+	writeJSON(w, hold)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+	}
+}
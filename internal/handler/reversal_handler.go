@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/audit"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/service"
+)
+
+type ReversalRequest struct {
+	// Amount — сумма возврата; если не задана, сторнируется вся еще не
+	// сторнированная часть транзакции.
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// ReverseTransaction сторнирует пополнение или списание целиком или частично.
+// Это операция бэк-офиса, поэтому нужен scope admin.
+func (h *WalletHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID := mux.Vars(r)["transactionId"]
+	details := audit.FromContext(r.Context())
+	details.Set(audit.ActionTransactionReverse, "")
+
+	var req ReversalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Слишком длинный ключ идемпотентности")
+		return
+	}
+	if !h.authorize(w, r, auth.ScopeAdmin) {
+		return
+	}
+	ctx := service.WithIdempotencyKey(r.Context(), clientIdempotencyKey(r, idempotencyKey))
+
+	transaction, err := h.WalletService.Reverse(ctx, transactionID, req.Amount)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка сторно: TransactionID=%s, Amount=%s", transactionID, req.Amount)
+		writeServiceError(w, err)
+		return
+	}
+	details.Set(audit.ActionTransactionReverse, transaction.WalletID)
+	details.SetTransaction(transaction)
+	writeJSON(w, transaction)
+}
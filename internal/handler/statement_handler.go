@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/service"
+	"github.com/sunriseex/test_wallet/internal/statement"
+)
+
+// statementWriteTimeout заменяет WriteTimeout сервера для выписок: большая
+// выписка пишется дольше обычного ответа.
+const statementWriteTimeout = 2 * time.Minute
+
+type statementFormat struct {
+	contentType string
+	newWriter   func(io.Writer) service.StatementWriter
+}
+
+var statementFormats = map[string]statementFormat{
+	"json": {
+		contentType: "application/json",
+		newWriter:   func(w io.Writer) service.StatementWriter { return statement.NewJSONWriter(w) },
+	},
+	"csv": {
+		contentType: "text/csv; charset=utf-8",
+		newWriter:   func(w io.Writer) service.StatementWriter { return statement.NewCSVWriter(w) },
+	},
+}
+
+// statementResponse выставляет заголовки ответа, только когда сервис начал
+// выписку: до этого ошибку еще можно отдать обычным JSON.
+type statementResponse struct {
+	service.StatementWriter
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (s *statementResponse) Begin(header model.StatementHeader) error {
+	s.started = true
+	s.w.Header().Set("Content-Type", s.contentType)
+	s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.filename))
+	s.w.WriteHeader(http.StatusOK)
+	return s.StatementWriter.Begin(header)
+}
+
+// GetStatement отдает выписку по кошельку за дни [from, to] (YYYY-MM-DD, UTC)
+// в формате format: json (по умолчанию) или csv. to по умолчанию — сегодня.
+func (h *WalletHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	walletID := mux.Vars(r)["walletId"]
+	if _, err := uuid.Parse(walletID); err != nil {
+		h.log(r).WithError(err).Errorf("Invalid wallet ID: %s", walletID)
+		writeServiceError(w, service.ErrInvalidWalletID)
+		return
+	}
+
+	query := r.URL.Query()
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = "json"
+	}
+	format, ok := statementFormats[formatName]
+	if !ok {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр format")
+		return
+	}
+	from, err := time.Parse(model.DateLayout, query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр from")
+		return
+	}
+	to := time.Now().UTC()
+	if value := query.Get("to"); value != "" {
+		if to, err = time.Parse(model.DateLayout, value); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр to")
+			return
+		}
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletRead, walletID) {
+		return
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(statementWriteTimeout)); err != nil {
+		h.log(r).WithError(err).Debug("Не удалось продлить таймаут записи выписки")
+	}
+
+	resp := &statementResponse{
+		StatementWriter: format.newWriter(w),
+		w:               w,
+		contentType:     format.contentType,
+		filename:        fmt.Sprintf("statement-%s-%s-%s.%s", walletID, from.Format(model.DateLayout), to.Format(model.DateLayout), formatName),
+	}
+	if err := h.WalletService.Statement(r.Context(), walletID, from, to, resp); err != nil {
+		if !resp.started {
+			h.log(r).WithError(err).Errorf("Ошибка формирования выписки: WalletID=%s", walletID)
+			writeServiceError(w, err)
+			return
+		}
+		// Заголовки уже отправлены: клиент получит оборванную выписку.
+		h.log(r).WithError(err).Errorf("Выписка прервана: %s", walletID)
+	}
+}
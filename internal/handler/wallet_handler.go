@@ -1,84 +1,349 @@
 package handler
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
+	"github.com/sunriseex/test_wallet/internal/audit"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
 	"github.com/sunriseex/test_wallet/internal/service"
 )
 
+const (
+	defaultTransactionsLimit = 50
+	maxTransactionsLimit     = 500
+	maxIdempotencyKeyLength  = 255
+	idempotencyKeyHeader     = "Idempotency-Key"
+)
+
 type RequestBody struct {
 	WalletID      string          `json:"walletId"`
 	OperationType string          `json:"operationType"`
 	Amount        decimal.Decimal `json:"amount"`
+	// Currency — код валюты ISO 4217. Должен совпадать с валютой кошелька;
+	// если не указан, используется валюта кошелька.
+	Currency   string `json:"currency,omitempty"`
+	ToWalletID string `json:"toWalletId,omitempty"`
+	RequestID  string `json:"requestId,omitempty"`
 }
+
+// JobQueue принимает операции на асинхронное выполнение. AddJob возвращает
+// false, если очередь заполнена или закрыта.
+type JobQueue interface {
+	AddJob(job service.Job) bool
+}
+
+type CreateWalletRequest struct {
+	WalletID string         `json:"walletId,omitempty"`
+	Currency string         `json:"currency,omitempty"`
+	OwnerID  string         `json:"ownerId,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Labels   []string       `json:"labels,omitempty"`
+}
+
+// UpdateWalletRequest — тело PATCH: отсутствующие поля не меняются,
+// metadata и labels заменяются целиком.
+type UpdateWalletRequest struct {
+	Status   *string        `json:"status,omitempty"`
+	OwnerID  *string        `json:"ownerId,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+	Labels   []string       `json:"labels,omitempty"`
+}
+
 type WalletHandler struct {
 	Logger        *logrus.Logger
 	WalletService service.WalletService
+	Jobs          JobQueue
 }
 
 func NewWalletHandler(
 	logger *logrus.Logger,
 	walletService service.WalletService,
+	jobs JobQueue,
 ) *WalletHandler {
 	return &WalletHandler{
 		Logger:        logger,
 		WalletService: walletService,
+		Jobs:          jobs,
+	}
+}
+
+// log возвращает запись логгера с request_id и wallet_id текущего запроса.
+func (h *WalletHandler) log(r *http.Request) *logrus.Entry {
+	return h.Logger.WithFields(logger.Fields(r.Context()))
+}
+
+func (h *WalletHandler) CreateWallet(w http.ResponseWriter, r *http.Request) {
+	var req CreateWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
+		return
+	}
+	details := audit.FromContext(r.Context())
+	details.Set(audit.ActionWalletCreate, req.WalletID)
+
+	if !h.authorize(w, r, auth.ScopeWalletManage) {
+		return
+	}
+	if principal, ok := endUser(r); ok {
+		if req.OwnerID != "" && req.OwnerID != principal.Subject {
+			h.forbid(w, r, principal, "cannot create a wallet for another owner")
+			return
+		}
+		req.OwnerID = principal.Subject
+	}
+
+	wallet, err := h.WalletService.CreateWallet(r.Context(), service.CreateWalletParams{
+		WalletID: req.WalletID,
+		Currency: req.Currency,
+		OwnerID:  req.OwnerID,
+		Metadata: req.Metadata,
+		Labels:   req.Labels,
+	})
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка создания кошелька: WalletID=%s", req.WalletID)
+		writeServiceError(w, err)
+		return
+	}
+	details.Set(audit.ActionWalletCreate, wallet.WalletID)
+	details.SetBalanceAfter(wallet.Balance)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/wallets/"+wallet.WalletID)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(wallet); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+	}
+}
+
+func (h *WalletHandler) UpdateWallet(w http.ResponseWriter, r *http.Request) {
+	walletID := mux.Vars(r)["walletId"]
+	audit.FromContext(r.Context()).Set(audit.ActionWalletUpdate, walletID)
+
+	var req UpdateWalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletManage, walletID) {
+		return
+	}
+	if principal, ok := endUser(r); ok && req.OwnerID != nil && *req.OwnerID != principal.Subject {
+		h.forbid(w, r, principal, "cannot transfer a wallet to another owner")
+		return
+	}
+	// Статус меняет только бэк-офис: иначе владелец мог бы разморозить
+	// кошелек, замороженный оператором.
+	if principal, ok := auth.FromContext(r.Context()); ok && req.Status != nil && !principal.HasScope(auth.ScopeAdmin) {
+		h.forbid(w, r, principal, "changing wallet status requires scope "+auth.ScopeAdmin)
+		return
+	}
+
+	wallet, err := h.WalletService.UpdateWallet(r.Context(), walletID, service.UpdateWalletParams{
+		Status:   req.Status,
+		OwnerID:  req.OwnerID,
+		Metadata: req.Metadata,
+		Labels:   req.Labels,
+	})
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка изменения кошелька: WalletID=%s", walletID)
+		writeServiceError(w, err)
+		return
+	}
+	audit.FromContext(r.Context()).SetBalances(wallet.Balance, wallet.Balance)
+	writeJSON(w, wallet)
+}
+
+func (h *WalletHandler) ListWallets(w http.ResponseWriter, r *http.Request) {
+	ownerID := r.URL.Query().Get("ownerId")
+	if ownerID == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Не указан параметр ownerId")
+		return
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletRead) {
+		return
+	}
+	if principal, ok := endUser(r); ok && ownerID != principal.Subject {
+		h.forbid(w, r, principal, "cannot list wallets of another owner")
+		return
 	}
+
+	wallets, err := h.WalletService.ListWalletsByOwner(r.Context(), ownerID)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка получения кошельков: OwnerID=%s", ownerID)
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, wallets)
 }
 
 func (h *WalletHandler) CreateOrUpdateWallet(w http.ResponseWriter, r *http.Request) {
 	var req RequestBody
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.Logger.WithError(err).Error("Ошибка декодирования запроса")
-		http.Error(w, "Неверный формат запроса", http.StatusBadRequest)
+		h.log(r).WithError(err).Error("Ошибка декодирования запроса")
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный формат запроса")
 		return
 	}
 
 	if _, err := uuid.Parse(req.WalletID); err != nil {
-		h.Logger.WithError(err).Errorf("Invalid wallet ID: %s", req.WalletID)
-		http.Error(w, "Invalid wallet ID format", http.StatusBadRequest)
+		h.log(r).WithError(err).Errorf("Invalid wallet ID: %s", req.WalletID)
+		writeServiceError(w, service.ErrInvalidWalletID)
 		return
 	}
+	r = r.WithContext(logger.WithWalletID(r.Context(), req.WalletID))
+	details := audit.FromContext(r.Context())
+	details.Set(operationActions[req.OperationType], req.WalletID)
 
 	if req.Amount.LessThanOrEqual(decimal.Zero) {
-		h.Logger.Error("Сумма должна быть положительной")
-		http.Error(w, "Сумма должна быть положительной", http.StatusBadRequest)
+		h.log(r).Error("Сумма должна быть положительной")
+		writeServiceError(w, service.ErrInvalidAmount)
 		return
 	}
 
-	ctx := r.Context()
+	idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+	if idempotencyKey == "" {
+		idempotencyKey = req.RequestID
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		h.log(r).Errorf("Слишком длинный ключ идемпотентности: %d", len(idempotencyKey))
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Слишком длинный ключ идемпотентности")
+		return
+	}
+
+	if scope, ok := operationScopes[req.OperationType]; ok && !h.authorize(w, r, scope, req.WalletID) {
+		return
+	}
+
+	if r.URL.Query().Get("async") == "true" {
+		details.Set(audit.ActionOperationSubmit, req.WalletID)
+		h.submitOperation(w, r, req, clientIdempotencyKey(r, idempotencyKey))
+		return
+	}
+
+	ctx := service.WithIdempotencyKey(r.Context(), clientIdempotencyKey(r, idempotencyKey))
+
+	var transaction model.Transaction
+	var err error
 	switch req.OperationType {
-	case "DEPOSIT":
-		if err := h.WalletService.Deposit(ctx, req.WalletID, req.Amount); err != nil {
-			h.Logger.WithError(err).Errorf("Ошибка при депозите: WalletID=%s, Amount=%s", req.WalletID, req.Amount)
-			http.Error(w, "Ошибка депозита", http.StatusBadRequest)
+	case model.OperationDeposit:
+		transaction, err = h.WalletService.Deposit(ctx, req.WalletID, req.Amount, req.Currency)
+		if err != nil {
+			h.log(r).WithError(err).Errorf("Ошибка при депозите: WalletID=%s, Amount=%s", req.WalletID, req.Amount)
+			writeServiceError(w, err)
+			return
+		}
+	case model.OperationWithdraw:
+		transaction, err = h.WalletService.Withdraw(ctx, req.WalletID, req.Amount, req.Currency)
+		if err != nil {
+			h.log(r).WithError(err).Errorf("Ошибка при снятии средств: WalletID=%s, Amount=%s", req.WalletID, req.Amount)
+			writeServiceError(w, err)
 			return
 		}
-	case "WITHDRAW":
-		if err := h.WalletService.Withdraw(ctx, req.WalletID, req.Amount); err != nil {
-			h.Logger.WithError(err).Errorf("Ошибка при снятии средств: WalletID=%s, Amount=%s", req.WalletID, req.Amount)
-			http.Error(w, "Ошибка снятия средств", http.StatusBadRequest)
+		h.log(r).Infof("Снятие средств успешно выполнено: WalletID=%s, Amount=%s", req.WalletID, req.Amount)
+	case model.OperationTransfer:
+		if _, err := uuid.Parse(req.ToWalletID); err != nil {
+			h.log(r).WithError(err).Errorf("Invalid destination wallet ID: %s", req.ToWalletID)
+			writeServiceError(w, service.ErrInvalidWalletID)
 			return
 		}
-		h.Logger.Infof("Снятие средств успешно выполнено: WalletID=%s, Amount=%s", req.WalletID, req.Amount)
+		transaction, err = h.WalletService.Transfer(ctx, req.WalletID, req.ToWalletID, req.Amount, req.Currency)
+		if err != nil {
+			h.log(r).WithError(err).Errorf("Ошибка при переводе: From=%s, To=%s, Amount=%s", req.WalletID, req.ToWalletID, req.Amount)
+			writeServiceError(w, err)
+			return
+		}
+		h.log(r).Infof("Перевод успешно выполнен: From=%s, To=%s, Amount=%s", req.WalletID, req.ToWalletID, req.Amount)
 	default:
-		h.Logger.Warnf("Неверный тип операции: %s", req.OperationType)
-		http.Error(w, "Неверный тип операции", http.StatusBadRequest)
+		h.log(r).Warnf("Неверный тип операции: %s", req.OperationType)
+		writeServiceError(w, service.ErrInvalidOperationType)
+		return
+
+	}
+
+	details.SetTransaction(transaction)
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(transaction); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+}
+
+// submitOperation сохраняет операцию и ставит ее в очередь пула воркеров.
+// Клиент получает 202 и опрашивает результат через GET /api/v1/operations/{id}.
+func (h *WalletHandler) submitOperation(w http.ResponseWriter, r *http.Request, req RequestBody, idempotencyKey string) {
+	if h.Jobs == nil {
+		writeServiceError(w, service.ErrQueueFull)
+		return
+	}
+
+	op, created, err := h.WalletService.CreateOperation(r.Context(), model.Operation{
+		WalletID:       req.WalletID,
+		OperationType:  req.OperationType,
+		Amount:         req.Amount,
+		Currency:       req.Currency,
+		ToWalletID:     req.ToWalletID,
+		IdempotencyKey: idempotencyKey,
+	})
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка создания операции: WalletID=%s, Type=%s", req.WalletID, req.OperationType)
+		writeServiceError(w, err)
 		return
+	}
 
+	if created && !h.Jobs.AddJob(service.Job{Operation: op, Ctx: context.WithoutCancel(r.Context())}) {
+		h.log(r).Warnf("Очередь операций заполнена: operation_id=%s", op.ID)
+		if _, err := h.WalletService.FailOperation(context.WithoutCancel(r.Context()), op.ID, service.ErrQueueFull); err != nil {
+			h.log(r).WithError(err).Errorf("Не удалось отметить операцию как неуспешную: %s", op.ID)
+		}
+		writeServiceError(w, service.ErrQueueFull)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Операция выполнена успешно"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/operations/"+op.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+	}
+}
+
+func (h *WalletHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	operationID := mux.Vars(r)["operationId"]
+
+	op, err := h.WalletService.GetOperation(r.Context(), operationID)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("Ошибка получения операции: OperationID=%s", operationID)
+		writeServiceError(w, err)
+		return
+	}
+	if !h.authorize(w, r, auth.ScopeWalletRead, op.WalletID) {
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+		return
+	}
 }
 
 func (h *WalletHandler) GetWalletBalance(w http.ResponseWriter, r *http.Request) {
@@ -86,38 +351,95 @@ func (h *WalletHandler) GetWalletBalance(w http.ResponseWriter, r *http.Request)
 	walletID := vars["walletId"]
 
 	if _, err := uuid.Parse(walletID); err != nil {
-		h.Logger.WithError(err).Errorf("Invalid wallet ID: %s", walletID)
-		http.Error(w, "Invalid wallet ID format", http.StatusBadRequest)
+		h.log(r).WithError(err).Errorf("Invalid wallet ID: %s", walletID)
+		writeServiceError(w, service.ErrInvalidWalletID)
+		return
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletRead, walletID) {
 		return
 	}
 
 	ctx := r.Context()
 	wallet, err := h.WalletService.GetBalance(ctx, walletID)
 	if err != nil {
-		h.Logger.WithError(err).Errorf("GetBalance error: %s", walletID)
-		if errors.Is(err, sql.ErrNoRows) {
-			http.Error(w, "Wallet not found", http.StatusNotFound)
-			return
-		}
-
-		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+		h.log(r).WithError(err).Errorf("GetBalance error: %s", walletID)
+		writeServiceError(w, err)
 		return
 	}
 
 	resp := struct {
-		WalletID string          `json:"walletId"`
-		Balance  decimal.Decimal `json:"balance"`
+		WalletID         string          `json:"walletId"`
+		Balance          decimal.Decimal `json:"balance"`
+		Currency         string          `json:"currency"`
+		Status           string          `json:"status"`
+		HeldBalance      decimal.Decimal `json:"heldBalance"`
+		AvailableBalance decimal.Decimal `json:"availableBalance"`
 	}{
-		WalletID: wallet.WalletID,
-		Balance:  wallet.Balance,
+		WalletID:         wallet.WalletID,
+		Balance:          wallet.Balance,
+		Currency:         wallet.Currency,
+		Status:           wallet.Status,
+		HeldBalance:      wallet.HeldBalance,
+		AvailableBalance: wallet.Available(),
 	}
 	w.Header().Set("Content-Type", "application/json")
 
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.Logger.WithError(err).Error("Ошибка кодирования ответа")
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
 		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
 		return
 
 	}
 
 }
+
+func (h *WalletHandler) GetWalletTransactions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	walletID := vars["walletId"]
+
+	if _, err := uuid.Parse(walletID); err != nil {
+		h.log(r).WithError(err).Errorf("Invalid wallet ID: %s", walletID)
+		writeServiceError(w, service.ErrInvalidWalletID)
+		return
+	}
+
+	limit, err := queryInt(r, "limit", defaultTransactionsLimit)
+	if err != nil || limit <= 0 || limit > maxTransactionsLimit {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр limit")
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Неверный параметр offset")
+		return
+	}
+
+	if !h.authorize(w, r, auth.ScopeWalletRead, walletID) {
+		return
+	}
+
+	ctx := r.Context()
+	transactions, err := h.WalletService.GetTransactions(ctx, walletID, limit, offset)
+	if err != nil {
+		h.log(r).WithError(err).Errorf("GetTransactions error: %s", walletID)
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(transactions); err != nil {
+		h.log(r).WithError(err).Error("Ошибка кодирования ответа")
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+}
+
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
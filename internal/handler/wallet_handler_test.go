@@ -2,7 +2,10 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -14,28 +17,80 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/sunriseex/test_wallet/internal/auth"
 	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+	"github.com/sunriseex/test_wallet/internal/service"
 )
 
 var (
-	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrInsufficientFunds = service.ErrInsufficientFunds
 	ErrInvalidOperation  = errors.New("invalid operation")
 )
 
+const conflictingIdempotencyKey = "conflicting-key"
+
+// newRequest — запрос, прошедший аутентификацию с auth.Anonymous, как при
+// AUTH_DISABLED=true.
+func newRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	return req.WithContext(auth.WithPrincipal(req.Context(), auth.Anonymous))
+}
+
 type mockWalletService struct {
 	mock.Mock
 }
 
-func (m *mockWalletService) Deposit(ctx context.Context, walletID string, amount decimal.Decimal) error {
-	return nil
+func (m *mockWalletService) Deposit(ctx context.Context, walletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error) {
+	if key, ok := service.IdempotencyKey(ctx); ok && strings.HasSuffix(key, ":"+conflictingIdempotencyKey) {
+		return model.Transaction{}, service.ErrIdempotencyKeyConflict
+	}
+	return model.Transaction{
+		WalletID:      walletID,
+		Amount:        amount,
+		OperationType: model.OperationDeposit,
+	}, nil
+}
+
+func (m *mockWalletService) Withdraw(ctx context.Context, walletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error) {
+	balance := decimal.NewFromInt(500)
+	if amount.GreaterThan(balance) {
+		return model.Transaction{}, ErrInsufficientFunds
+	}
+	return model.Transaction{
+		WalletID:      walletID,
+		Amount:        amount.Neg(),
+		OperationType: model.OperationWithdraw,
+	}, nil
 }
 
-func (m *mockWalletService) Withdraw(ctx context.Context, walletID string, amount decimal.Decimal) error {
+func (m *mockWalletService) Transfer(ctx context.Context, fromWalletID, toWalletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error) {
 	balance := decimal.NewFromInt(500)
 	if amount.GreaterThan(balance) {
-		return ErrInsufficientFunds
+		return model.Transaction{}, ErrInsufficientFunds
 	}
-	return nil
+	return model.Transaction{
+		WalletID:             fromWalletID,
+		Amount:               amount.Neg(),
+		OperationType:        model.OperationTransfer,
+		CounterpartyWalletID: toWalletID,
+	}, nil
+}
+
+func (m *mockWalletService) CreateWallet(ctx context.Context, params service.CreateWalletParams) (model.Wallet, error) {
+	return model.Wallet{WalletID: params.WalletID, Currency: params.Currency, Status: model.WalletStatusActive}, nil
+}
+
+func (m *mockWalletService) UpdateWallet(ctx context.Context, walletID string, params service.UpdateWalletParams) (model.Wallet, error) {
+	return model.Wallet{}, service.ErrWalletNotFound
+}
+
+func (m *mockWalletService) ListWalletsByOwner(ctx context.Context, ownerID string) ([]model.Wallet, error) {
+	return nil, nil
+}
+
+func (m *mockWalletService) ListWallets(ctx context.Context, query service.WalletQuery) (service.WalletPage, error) {
+	return service.WalletPage{Wallets: []model.Wallet{}}, nil
 }
 
 func (m *mockWalletService) GetBalance(ctx context.Context, walletID string) (model.Wallet, error) {
@@ -47,11 +102,106 @@ func (m *mockWalletService) GetBalance(ctx context.Context, walletID string) (mo
 	}, nil
 }
 
+func (m *mockWalletService) GetTransactions(ctx context.Context, walletID string, limit, offset int) ([]model.Transaction, error) {
+	return []model.Transaction{
+		{
+			ID:            "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			WalletID:      walletID,
+			Amount:        decimal.NewFromInt(500),
+			OperationType: model.OperationDeposit,
+			BalanceAfter:  decimal.NewFromInt(500),
+			CreatedAt:     time.Now(),
+		},
+	}, nil
+}
+
+func (m *mockWalletService) BalanceAt(ctx context.Context, walletID string, at time.Time) (model.HistoricalBalance, error) {
+	return model.HistoricalBalance{WalletID: walletID, Currency: "RUB", At: at, Balance: decimal.NewFromInt(300)}, nil
+}
+
+func (m *mockWalletService) DailyBalances(ctx context.Context, walletID string, from, to time.Time) (model.BalanceSeries, error) {
+	return model.BalanceSeries{
+		WalletID: walletID,
+		Currency: "RUB",
+		Days: []model.DailyBalance{
+			{Date: from.Format(model.DateLayout), Balance: decimal.NewFromInt(300)},
+		},
+	}, nil
+}
+
+func (m *mockWalletService) Statement(ctx context.Context, walletID string, from, to time.Time, w service.StatementWriter) error {
+	if err := w.Begin(model.StatementHeader{WalletID: walletID, Currency: "RUB", From: from, To: to.AddDate(0, 0, 1), OpeningBalance: decimal.NewFromInt(100)}); err != nil {
+		return err
+	}
+	err := w.Operation(model.Transaction{
+		ID:            "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		WalletID:      walletID,
+		Amount:        decimal.NewFromInt(50),
+		OperationType: model.OperationDeposit,
+		BalanceAfter:  decimal.NewFromInt(150),
+		CreatedAt:     from.Add(time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	return w.End(model.StatementSummary{ClosingBalance: decimal.NewFromInt(150), OperationCount: 1, TotalCredits: decimal.NewFromInt(50)})
+}
+
+func (m *mockWalletService) TrialBalance(ctx context.Context) (model.TrialBalance, error) {
+	return model.TrialBalance{Balanced: true, Reconciled: true}, nil
+}
+
+func (m *mockWalletService) Reverse(ctx context.Context, transactionID string, amount decimal.Decimal) (model.Transaction, error) {
+	return model.Transaction{}, service.ErrTransactionNotFound
+}
+
+func (m *mockWalletService) CreateOperation(ctx context.Context, op model.Operation) (model.Operation, bool, error) {
+	op.ID = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+	op.Status = model.OperationStatusPending
+	return op, true, nil
+}
+
+func (m *mockWalletService) GetOperation(ctx context.Context, operationID string) (model.Operation, error) {
+	return model.Operation{}, service.ErrOperationNotFound
+}
+
+func (m *mockWalletService) FailOperation(ctx context.Context, operationID string, cause error) (model.Operation, error) {
+	return model.Operation{ID: operationID, Status: model.OperationStatusFailed, ErrorCode: service.ErrorCode(cause)}, nil
+}
+
+func (m *mockWalletService) PlaceHold(ctx context.Context, walletID string, amount decimal.Decimal, ttl time.Duration) (model.Hold, error) {
+	return model.Hold{}, service.ErrInsufficientFunds
+}
+
+func (m *mockWalletService) GetHold(ctx context.Context, holdID string) (model.Hold, error) {
+	return model.Hold{}, service.ErrHoldNotFound
+}
+
+func (m *mockWalletService) CaptureHold(ctx context.Context, holdID string, amount decimal.Decimal) (model.Transaction, error) {
+	return model.Transaction{}, service.ErrHoldNotFound
+}
+
+func (m *mockWalletService) ReleaseHold(ctx context.Context, holdID string) (model.Hold, error) {
+	return model.Hold{}, service.ErrHoldNotFound
+}
+
+func (m *mockWalletService) CreateQuote(ctx context.Context, from, to string, ttl time.Duration) (model.Quote, error) {
+	return model.Quote{}, service.ErrRateNotAvailable
+}
+
+func (m *mockWalletService) Convert(ctx context.Context, fromWalletID, toWalletID string, amount decimal.Decimal, quoteID string) (model.Conversion, error) {
+	return model.Conversion{}, service.ErrQuoteNotFound
+}
+
+type fullQueue struct{}
+
+func (fullQueue) AddJob(service.Job) bool { return false }
+
 func TestCreateOrUpdateWallet(t *testing.T) {
 
 	svc := &mockWalletService{}
 	logger := logrus.New()
-	handler := NewWalletHandler(logger, svc)
+	handler := NewWalletHandler(logger, svc, nil)
 
 	testCases := []struct {
 		name           string
@@ -86,9 +236,30 @@ func TestCreateOrUpdateWallet(t *testing.T) {
 				"operationType": "WITHDRAW",
 				"amount": "1000000"
 			}`,
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusPaymentRequired,
 			mockResponse:   ErrInsufficientFunds,
 		},
+		{
+			name: "Перевод между кошельками",
+			requestBody: `{
+				"walletId": "550e8400-e29b-41d4-a716-446655440000",
+				"operationType": "TRANSFER",
+				"toWalletId": "c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b",
+				"amount": "50.00"
+			}`,
+			expectedStatus: http.StatusOK,
+			mockResponse:   nil,
+		},
+		{
+			name: "Перевод без получателя",
+			requestBody: `{
+				"walletId": "550e8400-e29b-41d4-a716-446655440000",
+				"operationType": "TRANSFER",
+				"amount": "50.00"
+			}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			mockResponse:   nil,
+		},
 		{
 			name: "Некорректная операция",
 			requestBody: `{
@@ -96,14 +267,14 @@ func TestCreateOrUpdateWallet(t *testing.T) {
 				"operationType": "UNKNOWN",
 				"amount": "50.00"
 			}`,
-			expectedStatus: http.StatusBadRequest,
+			expectedStatus: http.StatusUnprocessableEntity,
 			mockResponse:   ErrInvalidOperation,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			req := httptest.NewRequest("POST", "/api/v1/wallet", strings.NewReader(tc.requestBody))
+			req := newRequest("POST", "/api/v1/wallet", strings.NewReader(tc.requestBody))
 			req.Header.Set("Content-Type", "application/json")
 			w := httptest.NewRecorder()
 
@@ -119,12 +290,119 @@ func TestCreateOrUpdateWallet(t *testing.T) {
 
 }
 
+func TestCreateOrUpdateWallet_IdempotencyKey(t *testing.T) {
+	svc := &mockWalletService{}
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+
+	testCases := []struct {
+		name           string
+		header         string
+		requestBody    string
+		expectedStatus int
+	}{
+		{
+			name:   "Ключ в заголовке",
+			header: "key-1",
+			requestBody: `{
+				"walletId": "550e8400-e29b-41d4-a716-446655440000",
+				"operationType": "DEPOSIT",
+				"amount": "100.50"
+			}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Конфликт ключа в теле запроса",
+			requestBody: `{
+				"walletId": "550e8400-e29b-41d4-a716-446655440000",
+				"operationType": "DEPOSIT",
+				"amount": "100.50",
+				"requestId": "conflicting-key"
+			}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "Слишком длинный ключ",
+			header: strings.Repeat("k", 256),
+			requestBody: `{
+				"walletId": "550e8400-e29b-41d4-a716-446655440000",
+				"operationType": "DEPOSIT",
+				"amount": "100.50"
+			}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newRequest("POST", "/api/v1/wallet", strings.NewReader(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.header != "" {
+				req.Header.Set("Idempotency-Key", tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.CreateOrUpdateWallet(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("%s: Expected %d, got %d", tc.name, tc.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	svc := &mockWalletService{}
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+
+	req := newRequest("POST", "/api/v1/wallet", strings.NewReader(`{
+		"walletId": "550e8400-e29b-41d4-a716-446655440000",
+		"operationType": "WITHDRAW",
+		"amount": "1000000"
+	}`))
+	w := httptest.NewRecorder()
+	handler.CreateOrUpdateWallet(w, req)
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Expected JSON error body: %v", err)
+	}
+	if resp.Error.Code != "INSUFFICIENT_FUNDS" {
+		t.Errorf("Expected INSUFFICIENT_FUNDS, got %q", resp.Error.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %q", ct)
+	}
+}
+
+func TestWriteServiceError(t *testing.T) {
+	testCases := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{service.ErrWalletNotFound, http.StatusNotFound},
+		{service.ErrIdempotencyKeyConflict, http.StatusConflict},
+		{service.ErrInvalidWalletID, http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w (5 attempts)", service.ErrRetriesExhausted), http.StatusServiceUnavailable},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		writeServiceError(w, tc.err)
+		if w.Code != tc.expectedStatus {
+			t.Errorf("%v: Expected %d, got %d", tc.err, tc.expectedStatus, w.Code)
+		}
+	}
+}
+
 func TestGetWalletBalance(t *testing.T) {
 	svc := &mockWalletService{}
 	logger := logrus.New()
-	handler := NewWalletHandler(logger, svc)
+	handler := NewWalletHandler(logger, svc, nil)
 
-	req := httptest.NewRequest("GET", "/api/v1/wallets/550e8400-e29b-41d4-a716-446655440000", nil)
+	req := newRequest("GET", "/api/v1/wallets/550e8400-e29b-41d4-a716-446655440000", nil)
 	req = mux.SetURLVars(req, map[string]string{"walletId": "550e8400-e29b-41d4-a716-446655440000"})
 	w := httptest.NewRecorder()
 	handler.GetWalletBalance(w, req)
@@ -136,27 +414,625 @@ func TestGetWalletBalance(t *testing.T) {
 func TestInvalidWalletID(t *testing.T) {
 	svc := &mockWalletService{}
 	logger := logrus.New()
-	handler := NewWalletHandler(logger, svc)
-	req := httptest.NewRequest("GET", "/api/v1/wallets/invalid_id", nil)
+	handler := NewWalletHandler(logger, svc, nil)
+	req := newRequest("GET", "/api/v1/wallets/invalid_id", nil)
 	req = mux.SetURLVars(req, map[string]string{"walletId": "invalid_id"})
 	w := httptest.NewRecorder()
 	handler.GetWalletBalance(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("Expected 400, got %d", w.Code)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422, got %d", w.Code)
 	}
 }
 
 func TestWalletNotFound(t *testing.T) {
 	svc := &mockWalletService{}
 	logger := logrus.New()
-	handler := NewWalletHandler(logger, svc)
+	handler := NewWalletHandler(logger, svc, nil)
 
-	req := httptest.NewRequest("GET", "/api/v1/wallets/550e8400-e29b-41d4-446655440000", nil)
+	req := newRequest("GET", "/api/v1/wallets/550e8400-e29b-41d4-446655440000", nil)
 	req = mux.SetURLVars(req, map[string]string{"walletId": "550e8400-e29b-41d4-446655440000"})
 	w := httptest.NewRecorder()
 	handler.GetWalletBalance(w, req)
 
-	if w.Code != http.StatusBadRequest {
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422, got %d", w.Code)
+	}
+}
+
+func TestGetWalletTransactions(t *testing.T) {
+	svc := &mockWalletService{}
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{name: "По умолчанию", query: "", expectedStatus: http.StatusOK},
+		{name: "С пагинацией", query: "?limit=10&offset=20", expectedStatus: http.StatusOK},
+		{name: "Неверный limit", query: "?limit=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Слишком большой limit", query: "?limit=100000", expectedStatus: http.StatusBadRequest},
+		{name: "Отрицательный offset", query: "?offset=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newRequest("GET", "/api/v1/wallets/550e8400-e29b-41d4-a716-446655440000/transactions"+tc.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"walletId": "550e8400-e29b-41d4-a716-446655440000"})
+			w := httptest.NewRecorder()
+			handler.GetWalletTransactions(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("%s: Expected %d, got %d", tc.name, tc.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdateWallet_InMemoryService(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+
+	create := func(body string) int {
+		req := newRequest("POST", "/api/v1/wallets", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.CreateWallet(w, req)
+		return w.Code
+	}
+	if code := create(`{"walletId": "550e8400-e29b-41d4-a716-446655440000"}`); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+	if code := create(`{"walletId": "550e8400-e29b-41d4-a716-446655440000"}`); code != http.StatusConflict {
+		t.Fatalf("Expected 409, got %d", code)
+	}
+
+	steps := []struct {
+		body           string
+		expectedStatus int
+	}{
+		{`{"walletId": "c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b", "operationType": "DEPOSIT", "amount": "100"}`, http.StatusNotFound},
+		{`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "DEPOSIT", "amount": "100"}`, http.StatusOK},
+		{`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "WITHDRAW", "amount": "30"}`, http.StatusOK},
+		{`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "WITHDRAW", "amount": "71"}`, http.StatusPaymentRequired},
+		{`{"walletId": "c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b", "operationType": "WITHDRAW", "amount": "1"}`, http.StatusNotFound},
+	}
+	for _, step := range steps {
+		req := newRequest("POST", "/api/v1/wallet", strings.NewReader(step.body))
+		w := httptest.NewRecorder()
+		handler.CreateOrUpdateWallet(w, req)
+		if w.Code != step.expectedStatus {
+			t.Fatalf("%s: Expected %d, got %d", step.body, step.expectedStatus, w.Code)
+		}
+	}
+
+	req := newRequest("GET", "/api/v1/wallets/550e8400-e29b-41d4-a716-446655440000", nil)
+	req = mux.SetURLVars(req, map[string]string{"walletId": "550e8400-e29b-41d4-a716-446655440000"})
+	w := httptest.NewRecorder()
+	handler.GetWalletBalance(w, req)
+
+	var resp struct {
+		Balance decimal.Decimal `json:"balance"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+	if !resp.Balance.Equal(decimal.NewFromInt(70)) {
+		t.Errorf("Expected balance 70, got %s", resp.Balance)
+	}
+}
+
+func TestCreateOrUpdateWallet_Async(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	pool := service.NewWorkerPool(svc, 2, 10)
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, pool)
+	if _, err := svc.CreateWallet(context.Background(), service.CreateWalletParams{WalletID: "550e8400-e29b-41d4-a716-446655440000"}); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	req := newRequest("POST", "/api/v1/wallet?async=true", strings.NewReader(
+		`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "DEPOSIT", "amount": "100"}`))
+	w := httptest.NewRecorder()
+	handler.CreateOrUpdateWallet(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", w.Code)
+	}
+
+	var op model.Operation
+	if err := json.NewDecoder(w.Body).Decode(&op); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+	if w.Header().Get("Location") != "/api/v1/operations/"+op.ID {
+		t.Errorf("Unexpected Location: %q", w.Header().Get("Location"))
+	}
+	pool.Shutdown()
+
+	req = newRequest("GET", "/api/v1/operations/"+op.ID, nil)
+	req = mux.SetURLVars(req, map[string]string{"operationId": op.ID})
+	w = httptest.NewRecorder()
+	handler.GetOperation(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if err := json.NewDecoder(w.Body).Decode(&op); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+	if op.Status != model.OperationStatusSucceeded || op.TransactionID == "" {
+		t.Errorf("Expected succeeded operation with transaction, got %+v", op)
+	}
+}
+
+func TestCreateOrUpdateWallet_AsyncQueueFull(t *testing.T) {
+	svc := &mockWalletService{}
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, fullQueue{})
+
+	req := newRequest("POST", "/api/v1/wallet?async=true", strings.NewReader(
+		`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "DEPOSIT", "amount": "100"}`))
+	w := httptest.NewRecorder()
+	handler.CreateOrUpdateWallet(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+}
+
+func TestGetOperationNotFound(t *testing.T) {
+	svc := &mockWalletService{}
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+
+	req := newRequest("GET", "/api/v1/operations/6ba7b811-9dad-11d1-80b4-00c04fd430c8", nil)
+	req = mux.SetURLVars(req, map[string]string{"operationId": "6ba7b811-9dad-11d1-80b4-00c04fd430c8"})
+	w := httptest.NewRecorder()
+	handler.GetOperation(w, req)
+	if w.Code != http.StatusNotFound {
 		t.Errorf("Expected 404, got %d", w.Code)
 	}
 }
+
+func TestHolds_InMemoryService(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	if _, err := svc.CreateWallet(context.Background(), service.CreateWalletParams{WalletID: walletID}); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	req := newRequest("POST", "/api/v1/wallet", strings.NewReader(
+		`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "DEPOSIT", "amount": "100"}`))
+	w := httptest.NewRecorder()
+	handler.CreateOrUpdateWallet(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+
+	req = newRequest("POST", "/api/v1/wallets/"+walletID+"/holds", strings.NewReader(`{"amount": "80", "ttlSeconds": 600}`))
+	req = mux.SetURLVars(req, map[string]string{"walletId": walletID})
+	w = httptest.NewRecorder()
+	handler.PlaceHold(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", w.Code)
+	}
+	var hold model.Hold
+	if err := json.NewDecoder(w.Body).Decode(&hold); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+
+	req = newRequest("POST", "/api/v1/wallet", strings.NewReader(
+		`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "WITHDRAW", "amount": "30"}`))
+	w = httptest.NewRecorder()
+	handler.CreateOrUpdateWallet(w, req)
+	if w.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected 402, got %d", w.Code)
+	}
+
+	req = newRequest("POST", "/api/v1/holds/"+hold.ID+"/capture", nil)
+	req = mux.SetURLVars(req, map[string]string{"holdId": hold.ID})
+	w = httptest.NewRecorder()
+	handler.CaptureHold(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+
+	req = newRequest("POST", "/api/v1/holds/"+hold.ID+"/release", nil)
+	req = mux.SetURLVars(req, map[string]string{"holdId": hold.ID})
+	w = httptest.NewRecorder()
+	handler.ReleaseHold(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected 409, got %d", w.Code)
+	}
+
+	req = newRequest("GET", "/api/v1/wallets/"+walletID, nil)
+	req = mux.SetURLVars(req, map[string]string{"walletId": walletID})
+	w = httptest.NewRecorder()
+	handler.GetWalletBalance(w, req)
+
+	var resp struct {
+		Balance          decimal.Decimal `json:"balance"`
+		AvailableBalance decimal.Decimal `json:"availableBalance"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+	if !resp.Balance.Equal(decimal.NewFromInt(20)) || !resp.AvailableBalance.Equal(decimal.NewFromInt(20)) {
+		t.Errorf("Expected balance 20, got %s (available %s)", resp.Balance, resp.AvailableBalance)
+	}
+}
+
+func TestUpdateWallet_InMemoryService(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	if _, err := svc.CreateWallet(context.Background(), service.CreateWalletParams{WalletID: walletID}); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	testCases := []struct {
+		body           string
+		expectedStatus int
+	}{
+		{`{"status": "frozen"}`, http.StatusOK},
+		{`{"status": "deleted"}`, http.StatusUnprocessableEntity},
+		{`{"status": "closed"}`, http.StatusOK},
+		{`{"status": "active"}`, http.StatusConflict},
+	}
+	for _, tc := range testCases {
+		req := newRequest("PATCH", "/api/v1/wallets/"+walletID, strings.NewReader(tc.body))
+		req = mux.SetURLVars(req, map[string]string{"walletId": walletID})
+		w := httptest.NewRecorder()
+		handler.UpdateWallet(w, req)
+		if w.Code != tc.expectedStatus {
+			t.Fatalf("%s: Expected %d, got %d", tc.body, tc.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestListWallets_InMemoryService(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+
+	for _, body := range []string{
+		`{"ownerId": "user-42", "labels": ["savings"], "metadata": {"tier": "gold"}}`,
+		`{"ownerId": "user-42", "currency": "USD"}`,
+		`{"ownerId": "user-7"}`,
+	} {
+		req := newRequest("POST", "/api/v1/wallets", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.CreateWallet(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d", w.Code)
+		}
+	}
+
+	req := newRequest("GET", "/api/v1/wallets?ownerId=user-42", nil)
+	w := httptest.NewRecorder()
+	handler.ListWallets(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var wallets []model.Wallet
+	if err := json.NewDecoder(w.Body).Decode(&wallets); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(wallets) != 2 || wallets[0].OwnerID != "user-42" || wallets[0].Metadata["tier"] != "gold" {
+		t.Fatalf("Unexpected wallets: %+v", wallets)
+	}
+
+	req = newRequest("GET", "/api/v1/wallets", nil)
+	w = httptest.NewRecorder()
+	handler.ListWallets(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d", w.Code)
+	}
+}
+
+func TestSearchWallets_InvalidParams(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+
+	testCases := []struct {
+		query          string
+		expectedStatus int
+	}{
+		{"", http.StatusOK},
+		{"?status=frozen&minBalance=10&createdFrom=2024-01-01T00:00:00Z&sort=balance&order=desc", http.StatusOK},
+		{"?minBalance=abc", http.StatusBadRequest},
+		{"?createdTo=yesterday", http.StatusBadRequest},
+		{"?order=sideways", http.StatusBadRequest},
+		{"?sort=currency", http.StatusUnprocessableEntity},
+		{"?limit=1000", http.StatusUnprocessableEntity},
+		{"?cursor=not-a-cursor", http.StatusUnprocessableEntity},
+	}
+	for _, tc := range testCases {
+		req := newRequest("GET", "/api/v1/admin/wallets"+tc.query, nil)
+		w := httptest.NewRecorder()
+		handler.SearchWallets(w, req)
+		if w.Code != tc.expectedStatus {
+			t.Errorf("%q: Expected %d, got %d", tc.query, tc.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestAuthorization_InMemoryService(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	logger := logrus.New()
+	handler := NewWalletHandler(logger, svc, nil)
+	ctx := context.Background()
+
+	own, err := svc.CreateWallet(ctx, service.CreateWalletParams{OwnerID: "user-42"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	foreign, err := svc.CreateWallet(ctx, service.CreateWalletParams{OwnerID: "user-7"})
+	if err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	user := auth.Principal{Subject: "user-42", Method: auth.MethodJWT, Scopes: []string{auth.ScopeWalletRead, auth.ScopeWalletDeposit, auth.ScopeWalletManage}}
+	readOnly := auth.Principal{Subject: "user-42", Method: auth.MethodJWT, Scopes: []string{auth.ScopeWalletRead}}
+	backoffice := auth.Principal{Subject: "backoffice", Method: auth.MethodAPIKey, Scopes: []string{auth.ScopeAdmin}}
+	payments := auth.Principal{Subject: "payments", Method: auth.MethodAPIKey, Scopes: []string{auth.ScopeWalletDeposit}}
+
+	deposit := func(walletID string) string {
+		return `{"walletId": "` + walletID + `", "operationType": "DEPOSIT", "amount": "10"}`
+	}
+	testCases := []struct {
+		name           string
+		principal      auth.Principal
+		method, path   string
+		body           string
+		vars           map[string]string
+		handle         func(http.ResponseWriter, *http.Request)
+		expectedStatus int
+	}{
+		{"deposit to own wallet", user, "POST", "/api/v1/wallet", deposit(own.WalletID), nil, handler.CreateOrUpdateWallet, http.StatusOK},
+		{"deposit to foreign wallet", user, "POST", "/api/v1/wallet", deposit(foreign.WalletID), nil, handler.CreateOrUpdateWallet, http.StatusForbidden},
+		{"deposit without scope", readOnly, "POST", "/api/v1/wallet", deposit(own.WalletID), nil, handler.CreateOrUpdateWallet, http.StatusForbidden},
+		{"withdraw without scope", user, "POST", "/api/v1/wallet", `{"walletId": "` + own.WalletID + `", "operationType": "WITHDRAW", "amount": "1"}`, nil, handler.CreateOrUpdateWallet, http.StatusForbidden},
+		{"service deposits to any wallet", payments, "POST", "/api/v1/wallet", deposit(foreign.WalletID), nil, handler.CreateOrUpdateWallet, http.StatusOK},
+		{"read own balance", readOnly, "GET", "/api/v1/wallets/" + own.WalletID, "", map[string]string{"walletId": own.WalletID}, handler.GetWalletBalance, http.StatusOK},
+		{"read foreign balance", readOnly, "GET", "/api/v1/wallets/" + foreign.WalletID, "", map[string]string{"walletId": foreign.WalletID}, handler.GetWalletBalance, http.StatusForbidden},
+		{"freeze foreign wallet", user, "PATCH", "/api/v1/wallets/" + foreign.WalletID, `{"status": "frozen"}`, map[string]string{"walletId": foreign.WalletID}, handler.UpdateWallet, http.StatusForbidden},
+		{"admin freezes wallet", backoffice, "PATCH", "/api/v1/wallets/" + own.WalletID, `{"status": "frozen"}`, map[string]string{"walletId": own.WalletID}, handler.UpdateWallet, http.StatusOK},
+		{"owner unfreezes wallet", user, "PATCH", "/api/v1/wallets/" + own.WalletID, `{"status": "active"}`, map[string]string{"walletId": own.WalletID}, handler.UpdateWallet, http.StatusForbidden},
+		{"owner edits labels", user, "PATCH", "/api/v1/wallets/" + own.WalletID, `{"labels": ["savings"]}`, map[string]string{"walletId": own.WalletID}, handler.UpdateWallet, http.StatusOK},
+		{"admin unfreezes wallet", backoffice, "PATCH", "/api/v1/wallets/" + own.WalletID, `{"status": "active"}`, map[string]string{"walletId": own.WalletID}, handler.UpdateWallet, http.StatusOK},
+		{"give wallet away", user, "PATCH", "/api/v1/wallets/" + own.WalletID, `{"ownerId": "user-7"}`, map[string]string{"walletId": own.WalletID}, handler.UpdateWallet, http.StatusForbidden},
+		{"create wallet for another owner", user, "POST", "/api/v1/wallets", `{"ownerId": "user-7"}`, nil, handler.CreateWallet, http.StatusForbidden},
+		{"list own wallets", user, "GET", "/api/v1/wallets?ownerId=user-42", "", nil, handler.ListWallets, http.StatusOK},
+		{"list foreign wallets", user, "GET", "/api/v1/wallets?ownerId=user-7", "", nil, handler.ListWallets, http.StatusForbidden},
+		{"admin search as user", user, "GET", "/api/v1/admin/wallets", "", nil, handler.SearchWallets, http.StatusForbidden},
+		{"admin search as admin", backoffice, "GET", "/api/v1/admin/wallets", "", nil, handler.SearchWallets, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		req := newRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), tc.principal))
+		if tc.vars != nil {
+			req = mux.SetURLVars(req, tc.vars)
+		}
+		w := httptest.NewRecorder()
+		tc.handle(w, req)
+		if w.Code != tc.expectedStatus {
+			t.Errorf("%s: Expected %d, got %d: %s", tc.name, tc.expectedStatus, w.Code, w.Body.String())
+		}
+	}
+
+	req := newRequest("POST", "/api/v1/wallets", strings.NewReader(`{}`))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), user))
+	w := httptest.NewRecorder()
+	handler.CreateWallet(w, req)
+	var created model.Wallet
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil || created.OwnerID != "user-42" {
+		t.Errorf("Expected wallet owned by user-42, got %+v (%v)", created, err)
+	}
+}
+
+func TestIdempotencyKey_ScopedByClient(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	handler := NewWalletHandler(logrus.New(), svc, nil)
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	if _, err := svc.CreateWallet(context.Background(), service.CreateWalletParams{WalletID: walletID}); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+
+	deposit := func(principal auth.Principal, amount string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/wallet", strings.NewReader(
+			`{"walletId": "`+walletID+`", "operationType": "DEPOSIT", "amount": "`+amount+`"}`))
+		req.Header.Set(idempotencyKeyHeader, "order-1")
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		w := httptest.NewRecorder()
+		handler.CreateOrUpdateWallet(w, req)
+		return w
+	}
+
+	payments := auth.Principal{Subject: "payments", Method: auth.MethodAPIKey, Scopes: []string{auth.ScopeWalletDeposit}}
+	billing := auth.Principal{Subject: "billing", Method: auth.MethodAPIKey, Scopes: []string{auth.ScopeWalletDeposit}}
+	if w := deposit(payments, "10"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if w := deposit(billing, "20"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for the same key from another client, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := deposit(payments, "10.00"); w.Code != http.StatusOK {
+		t.Fatalf("Expected replay for the same amount with trailing zeros, got %d: %s", w.Code, w.Body.String())
+	}
+
+	wallet, err := svc.GetBalance(context.Background(), walletID)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if !wallet.Balance.Equal(decimal.NewFromInt(30)) {
+		t.Errorf("Expected balance 30, got %s", wallet.Balance)
+	}
+}
+
+func TestAuthorization_NoPrincipal(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	handler := NewWalletHandler(logrus.New(), svc, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/wallet", strings.NewReader(
+		`{"walletId": "550e8400-e29b-41d4-a716-446655440000", "operationType": "WITHDRAW", "amount": "10"}`))
+	w := httptest.NewRecorder()
+	handler.CreateOrUpdateWallet(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", w.Code)
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate header")
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/admin/ledger/trial-balance", nil)
+	w = httptest.NewRecorder()
+	handler.TrialBalance(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", w.Code)
+	}
+}
+
+func TestBalanceHistory(t *testing.T) {
+	svc := &mockWalletService{}
+	handler := NewWalletHandler(logrus.New(), svc, nil)
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+
+	testCases := []struct {
+		name           string
+		path           string
+		handle         http.HandlerFunc
+		expectedStatus int
+	}{
+		{name: "Текущий баланс", path: "/balance", handle: handler.GetBalanceAt, expectedStatus: http.StatusOK},
+		{name: "Баланс на момент", path: "/balance?at=2026-01-31T23:59:59Z", handle: handler.GetBalanceAt, expectedStatus: http.StatusOK},
+		{name: "Неверный at", path: "/balance?at=2026-01-31", handle: handler.GetBalanceAt, expectedStatus: http.StatusBadRequest},
+		{name: "Дневная история", path: "/balance/daily?from=2026-01-01&to=2026-01-31", handle: handler.GetDailyBalances, expectedStatus: http.StatusOK},
+		{name: "Без from", path: "/balance/daily", handle: handler.GetDailyBalances, expectedStatus: http.StatusBadRequest},
+		{name: "Неверный to", path: "/balance/daily?from=2026-01-01&to=31.01.2026", handle: handler.GetDailyBalances, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newRequest("GET", "/api/v1/wallets/"+walletID+tc.path, nil)
+			req = mux.SetURLVars(req, map[string]string{"walletId": walletID})
+			w := httptest.NewRecorder()
+			tc.handle(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("%s: Expected %d, got %d", tc.name, tc.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestReverseTransaction_InMemoryService(t *testing.T) {
+	svc := service.NewWalletService(repository.NewMemoryRepository(), nil)
+	handler := NewWalletHandler(logrus.New(), svc, nil)
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	if _, err := svc.CreateWallet(context.Background(), service.CreateWalletParams{WalletID: walletID}); err != nil {
+		t.Fatalf("CreateWallet: %v", err)
+	}
+	deposit, err := svc.Deposit(context.Background(), walletID, decimal.NewFromInt(100), "")
+	if err != nil {
+		t.Fatalf("Deposit: %v", err)
+	}
+
+	reverse := func(transactionID, body string) *httptest.ResponseRecorder {
+		req := newRequest("POST", "/api/v1/transactions/"+transactionID+"/reversals", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"transactionId": transactionID})
+		w := httptest.NewRecorder()
+		handler.ReverseTransaction(w, req)
+		return w
+	}
+
+	w := reverse(deposit.ID, `{"amount": "30"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var reversal model.Transaction
+	if err := json.NewDecoder(w.Body).Decode(&reversal); err != nil {
+		t.Fatalf("Expected JSON body: %v", err)
+	}
+	if reversal.OperationType != model.OperationReversal || reversal.ReversalOf != deposit.ID ||
+		!reversal.Amount.Equal(decimal.NewFromInt(-30)) || !reversal.BalanceAfter.Equal(decimal.NewFromInt(70)) {
+		t.Errorf("Unexpected reversal: %+v", reversal)
+	}
+
+	if w := reverse(deposit.ID, `{"amount": "80"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for reversal above the remaining amount, got %d", w.Code)
+	}
+	if w := reverse(deposit.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for reversal of the remaining amount, got %d", w.Code)
+	}
+	if w := reverse(deposit.ID, ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for repeated reversal, got %d", w.Code)
+	}
+	if w := reverse(reversal.ID, ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for reversal of a reversal, got %d", w.Code)
+	}
+	if w := reverse("6ba7b810-9dad-11d1-80b4-00c04fd430c8", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown transaction, got %d", w.Code)
+	}
+}
+
+func TestGetStatement(t *testing.T) {
+	svc := &mockWalletService{}
+	handler := NewWalletHandler(logrus.New(), svc, nil)
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := newRequest("GET", "/api/v1/wallets/"+walletID+"/statement"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"walletId": walletID})
+		w := httptest.NewRecorder()
+		handler.GetStatement(w, req)
+		return w
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		w := get("?from=2026-01-01&to=2026-01-31")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+		var body struct {
+			model.StatementHeader
+			Operations []model.Transaction `json:"operations"`
+			model.StatementSummary
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Invalid JSON: %v: %s", err, w.Body.String())
+		}
+		if !body.OpeningBalance.Equal(decimal.NewFromInt(100)) || !body.ClosingBalance.Equal(decimal.NewFromInt(150)) ||
+			len(body.Operations) != 1 || body.OperationCount != 1 {
+			t.Errorf("Unexpected statement: %s", w.Body.String())
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		w := get("?from=2026-01-01&to=2026-01-31&format=csv")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Errorf("Unexpected Content-Type %q", ct)
+		}
+		if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "statement-"+walletID+"-2026-01-01-2026-01-31.csv") {
+			t.Errorf("Unexpected Content-Disposition %q", cd)
+		}
+		expected := "created_at,transaction_id,operation_type,amount,balance_after,counterparty_wallet_id,reversal_of\n" +
+			"2026-01-01T00:00:00Z,,OPENING_BALANCE,,100,,\n" +
+			"2026-01-01T01:00:00Z,6ba7b810-9dad-11d1-80b4-00c04fd430c8,DEPOSIT,50,150,,\n" +
+			"2026-02-01T00:00:00Z,,CLOSING_BALANCE,,150,,\n"
+		if w.Body.String() != expected {
+			t.Errorf("Unexpected CSV:\n%s", w.Body.String())
+		}
+	})
+
+	for _, query := range []string{"?from=2026-01-01&format=pdf", "", "?from=01.01.2026", "?from=2026-01-01&to=x"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: Expected 400, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	FieldRequestID = "request_id"
+	FieldWalletID  = "wallet_id"
+)
+
+type fieldsKey struct{}
+
+// WithFields возвращает контекст, в котором к уже накопленным полям
+// добавлены fields. Все строки, записанные через FromContext, их получат.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := make(logrus.Fields, len(fields))
+	for k, v := range Fields(ctx) {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithRequestID добавляет идентификатор запроса в поля контекста.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return WithFields(ctx, logrus.Fields{FieldRequestID: requestID})
+}
+
+// WithWalletID добавляет идентификатор кошелька в поля контекста.
+func WithWalletID(ctx context.Context, walletID string) context.Context {
+	return WithFields(ctx, logrus.Fields{FieldWalletID: walletID})
+}
+
+// RequestID возвращает идентификатор запроса из контекста или "".
+func RequestID(ctx context.Context) string {
+	id, _ := Fields(ctx)[FieldRequestID].(string)
+	return id
+}
+
+// Fields возвращает поля, накопленные в контексте. Результат нельзя менять.
+func Fields(ctx context.Context) logrus.Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	return fields
+}
+
+// FromContext возвращает запись глобального логгера с полями из контекста.
+func FromContext(ctx context.Context) *logrus.Entry {
+	return Log.WithFields(Fields(ctx))
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "wallet"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Operations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operations_total",
+		Help:      "Balance operations by type and result (success or error code).",
+	}, []string{"operation", "result"})
+
+	OperationAmount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operation_amount_total",
+		Help:      "Sum of amounts of successful balance operations by type.",
+	}, []string{"operation"})
+
+	TxRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tx_retries_total",
+		Help:      "Transaction retries in executeWithRetry by SQLSTATE of the retried error.",
+	}, []string{"sqlstate"})
+
+	TxGiveUps = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tx_give_ups_total",
+		Help:      "Transactions abandoned after exhausting retries by SQLSTATE of the last error.",
+	}, []string{"sqlstate"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by the rate limiter by route and exhausted bucket (client or wallet).",
+	}, []string{"route", "bucket"})
+
+	AuditWriteErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_write_errors_total",
+		Help:      "Audited requests whose audit entry could not be stored.",
+	})
+)
+
+// RegisterWorkerQueue публикует текущую глубину очереди пула воркеров.
+func RegisterWorkerQueue(depth func() int) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_queue_depth",
+		Help:      "Jobs waiting in the worker pool queue.",
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// RegisterDBStats публикует статистику пула соединений sql.DB.
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "wallet_db"))
+}
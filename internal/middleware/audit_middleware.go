@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/sunriseex/test_wallet/internal/audit"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/metrics"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// maxErrorBodyCapture — сколько байт ответа читается, чтобы достать код ошибки.
+const maxErrorBodyCapture = 1 << 10
+
+// AuditRecorder сохраняет запись журнала аудита.
+type AuditRecorder interface {
+	Record(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error)
+}
+
+// AuditMiddleware записывает в журнал каждый изменяющий запрос (все методы,
+// кроме GET, HEAD и OPTIONS): кто, что, с каким кошельком, откуда и чем
+// закончилось. Действие и балансы задает обработчик через audit.Details;
+// результат — success или код ошибки из тела ответа.
+func AuditMiddleware(recorder AuditRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, details := audit.WithDetails(r.Context())
+			body := &headBuffer{limit: maxErrorBodyCapture}
+			rw := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			rw.Tee(body)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			entry := model.AuditEntry{
+				Actor:         "anonymous",
+				Action:        details.Action,
+				WalletID:      details.WalletID,
+				BalanceBefore: details.BalanceBefore,
+				BalanceAfter:  details.BalanceAfter,
+				ClientIP:      clientIP(r),
+				RequestID:     logger.RequestID(ctx),
+				Result:        auditResult(rw.Status(), body.data),
+			}
+			if principal, ok := auth.FromContext(ctx); ok {
+				entry.Actor = principal.Subject
+				entry.AuthMethod = principal.Method
+			}
+			if entry.Action == "" {
+				entry.Action = r.Method + " " + routeTemplate(r)
+			}
+			if entry.WalletID == "" {
+				entry.WalletID = mux.Vars(r)["walletId"]
+			}
+			if _, err := recorder.Record(context.WithoutCancel(ctx), entry); err != nil {
+				metrics.AuditWriteErrors.Inc()
+				// Запрос уже выполнен, поэтому запись пишется в лог целиком, чтобы ее
+				// можно было восстановить в журнале.
+				logger.FromContext(ctx).WithError(err).WithFields(logrus.Fields{
+					logger.FieldWalletID: entry.WalletID,
+					"actor":              entry.Actor,
+					"auth_method":        entry.AuthMethod,
+					"action":             entry.Action,
+					"balance_before":     entry.BalanceBefore,
+					"balance_after":      entry.BalanceAfter,
+					"client_ip":          entry.ClientIP,
+					"result":             entry.Result,
+				}).Error("Ошибка записи в журнал аудита")
+			}
+		})
+	}
+}
+
+// auditResult — success для 2xx/3xx, иначе код из ErrorResponse или HTTP_<статус>.
+func auditResult(status int, body []byte) string {
+	if status < http.StatusBadRequest {
+		return model.AuditResultSuccess
+	}
+	var resp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error.Code != "" {
+		return resp.Error.Code
+	}
+	return "HTTP_" + strconv.Itoa(status)
+}
+
+// headBuffer запоминает первые limit байт ответа и молча отбрасывает остальное.
+type headBuffer struct {
+	data  []byte
+	limit int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
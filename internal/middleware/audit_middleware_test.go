@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/sunriseex/test_wallet/internal/audit"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+type recordedAudit []model.AuditEntry
+
+func (r *recordedAudit) Record(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	*r = append(*r, entry)
+	return entry, nil
+}
+
+type failingAudit struct{}
+
+func (failingAudit) Record(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	return model.AuditEntry{}, errors.New("connection refused")
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	var recorded recordedAudit
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.Principal{Subject: "backoffice", Method: auth.MethodAPIKey}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	})
+	r.Use(RequestIDMiddleware)
+	r.Use(AuditMiddleware(&recorded))
+	r.HandleFunc("/api/v1/wallets/{walletId}/holds", func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.RawQuery, "fail") {
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"error":{"code":"INSUFFICIENT_FUNDS","message":"insufficient funds"}}`))
+			return
+		}
+		details := audit.FromContext(r.Context())
+		details.Set(audit.ActionHoldPlace, mux.Vars(r)["walletId"])
+		details.SetBalances(decimal.NewFromInt(10), decimal.NewFromInt(10))
+	}).Methods("POST", "GET")
+
+	for _, target := range []string{"/api/v1/wallets/w-1/holds", "/api/v1/wallets/w-2/holds?fail"} {
+		req := httptest.NewRequest("POST", target, nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		req.RemoteAddr = "10.1.2.3:5555"
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/wallets/w-1/holds", nil))
+
+	if len(recorded) != 2 {
+		t.Fatalf("Expected 2 audit entries (GET is not audited), got %d", len(recorded))
+	}
+
+	ok := recorded[0]
+	if ok.Actor != "backoffice" || ok.AuthMethod != auth.MethodAPIKey || ok.Action != audit.ActionHoldPlace ||
+		ok.WalletID != "w-1" || ok.ClientIP != "10.1.2.3" || ok.RequestID != "req-1" || ok.Result != model.AuditResultSuccess {
+		t.Errorf("Unexpected audit entry: %+v", ok)
+	}
+	if ok.BalanceAfter == nil || !ok.BalanceAfter.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("Expected balanceAfter 10, got %v", ok.BalanceAfter)
+	}
+
+	failed := recorded[1]
+	if failed.Result != "INSUFFICIENT_FUNDS" {
+		t.Errorf("Expected result INSUFFICIENT_FUNDS, got %q", failed.Result)
+	}
+	if failed.Action != "POST /api/v1/wallets/{walletId}/holds" || failed.WalletID != "w-2" {
+		t.Errorf("Expected route action and wallet from path, got %q, %q", failed.Action, failed.WalletID)
+	}
+}
+
+func TestAuditMiddleware_RecordErrorIsLogged(t *testing.T) {
+	hook := logtest.NewLocal(logger.Log)
+	defer hook.Reset()
+
+	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware)
+	r.Use(AuditMiddleware(failingAudit{}))
+	r.HandleFunc("/api/v1/wallets/{walletId}/holds", func(w http.ResponseWriter, r *http.Request) {
+		audit.FromContext(r.Context()).Set(audit.ActionHoldPlace, mux.Vars(r)["walletId"])
+	}).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/api/v1/wallets/w-1/holds", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.ErrorLevel {
+		t.Fatalf("Expected error log entry, got %+v", entry)
+	}
+	for field, expected := range map[string]string{
+		logger.FieldRequestID: "req-1",
+		logger.FieldWalletID:  "w-1",
+		"actor":               "anonymous",
+		"action":              audit.ActionHoldPlace,
+		"result":              model.AuditResultSuccess,
+	} {
+		if entry.Data[field] != expected {
+			t.Errorf("Expected %s=%q, got %v", field, expected, entry.Data[field])
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/logger"
+)
+
+const codeUnauthorized = "UNAUTHORIZED"
+
+// AuthMiddleware пропускает только запросы с действительным API-ключом или
+// JWT и кладет клиента в контекст (auth.FromContext).
+func AuthMiddleware(authenticator *auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				logger.FromContext(r.Context()).Warnf("Отказ в аутентификации: [%s] %s: %v", r.Method, r.URL.Path, err)
+				message := "invalid credentials"
+				if errors.Is(err, auth.ErrNoCredentials) {
+					message = "authentication required"
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wallet"`)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]any{
+					"error": map[string]string{"code": codeUnauthorized, "message": message},
+				})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// AnonymousMiddleware кладет в контекст auth.Anonymous. Используется только
+// при явно отключенной аутентификации.
+func AnonymousMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Anonymous)))
+	})
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sunriseex/test_wallet/internal/auth"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]auth.APIKey{{Name: "backoffice", Hash: auth.HashAPIKey("s3cret")}}, auth.JWTConfig{})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	var subject string
+	h := AuthMiddleware(authenticator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := auth.FromContext(r.Context())
+		subject = p.Subject
+	}))
+
+	testCases := []struct {
+		key            string
+		expectedStatus int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusUnauthorized},
+		{"s3cret", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/api/v1/wallets", nil)
+		if tc.key != "" {
+			req.Header.Set(auth.APIKeyHeader, tc.key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.expectedStatus {
+			t.Errorf("key %q: Expected %d, got %d", tc.key, tc.expectedStatus, w.Code)
+		}
+	}
+	if subject != "backoffice" {
+		t.Errorf("Expected principal backoffice, got %q", subject)
+	}
+}
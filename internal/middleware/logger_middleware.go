@@ -13,8 +13,8 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 		start := time.Now()
 		rw := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
-		next.ServeHTTP(w, r)
-		logger.Log.Infof(
+		next.ServeHTTP(rw, r)
+		logger.FromContext(r.Context()).Infof(
 			"[%s] %s %d %s",
 			r.Method,
 			r.URL.Path,
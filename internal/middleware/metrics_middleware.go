@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/mux"
+	"github.com/sunriseex/test_wallet/internal/metrics"
+)
+
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(rw, r)
+
+		status := rw.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := []string{r.Method, routeTemplate(r), strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeTemplate возвращает шаблон маршрута (/api/v1/wallets/{walletId}),
+// чтобы ID кошельков не раздували кардинальность меток.
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unmatched"
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "unmatched"
+	}
+	return template
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sunriseex/test_wallet/internal/metrics"
+)
+
+func TestMetricsMiddleware_UsesRouteTemplate(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(MetricsMiddleware)
+	r.HandleFunc("/api/v1/wallets/{walletId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+
+	counter := metrics.HTTPRequests.WithLabelValues("GET", "/api/v1/wallets/{walletId}", "404")
+	before := testutil.ToFloat64(counter)
+
+	for _, id := range []string{"550e8400-e29b-41d4-a716-446655440000", "c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b"} {
+		req := httptest.NewRequest("GET", "/api/v1/wallets/"+id, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(counter) - before; got != 2 {
+		t.Errorf("Expected 2 requests for route template, got %v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sunriseex/test_wallet/internal/auth"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/metrics"
+	"github.com/sunriseex/test_wallet/internal/ratelimit"
+)
+
+const (
+	codeRateLimited = "RATE_LIMITED"
+	// maxBodyPeek — сколько байт тела читается в поисках ID кошельков.
+	maxBodyPeek = 64 << 10
+)
+
+// RateLimitMiddleware ограничивает частоту запросов по клиенту и по
+// кошельку. Должен стоять после AuthMiddleware, чтобы клиент определялся по
+// учетным данным, а не по IP. Если хранилище лимитов недоступно, запрос
+// пропускается.
+func RateLimitMiddleware(store ratelimit.Store, rules ratelimit.Rules) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			rule, space := rules.For(r.Method, route)
+
+			type check struct {
+				bucket, key string
+				limit       ratelimit.Limit
+			}
+			var checks []check
+			if rule.Client.Enabled() {
+				checks = append(checks, check{"client", "client:" + space + ":" + clientKey(r), rule.Client})
+			}
+			if rule.Wallet.Enabled() {
+				for _, walletID := range requestWalletIDs(r) {
+					checks = append(checks, check{"wallet", "wallet:" + space + ":" + walletID, rule.Wallet})
+				}
+			}
+
+			for _, c := range checks {
+				result, err := store.Take(r.Context(), c.key, c.limit)
+				if err != nil {
+					logger.FromContext(r.Context()).WithError(err).Warnf("Хранилище лимитов недоступно, запрос пропущен: %s", c.key)
+					continue
+				}
+				if result.Allowed {
+					continue
+				}
+
+				metrics.RateLimited.WithLabelValues(route, c.bucket).Inc()
+				logger.FromContext(r.Context()).Warnf("Превышен лимит запросов: [%s] %s, %s", r.Method, r.URL.Path, c.key)
+				retryAfter := int(math.Max(1, math.Ceil(result.RetryAfter.Seconds())))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(map[string]any{
+					"error": map[string]string{"code": codeRateLimited, "message": "too many requests for " + c.bucket},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey — аутентифицированный клиент, а без аутентификации — IP.
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok && principal.Method != auth.MethodNone {
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP — адрес клиента из соединения без порта.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestWalletIDs собирает ID кошельков из пути и JSON-тела запроса. Тело
+// после чтения восстанавливается для обработчика.
+func requestWalletIDs(r *http.Request) []string {
+	var ids []string
+	if walletID := mux.Vars(r)["walletId"]; walletID != "" {
+		ids = append(ids, walletID)
+	}
+	if r.Body == nil || r.Method == http.MethodGet {
+		return ids
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, maxBodyPeek))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+	if err != nil {
+		return ids
+	}
+
+	var body struct {
+		WalletID     string `json:"walletId"`
+		ToWalletID   string `json:"toWalletId"`
+		FromWalletID string `json:"fromWalletId"`
+	}
+	if json.Unmarshal(peeked, &body) != nil {
+		return ids
+	}
+	for _, id := range []string{body.WalletID, body.FromWalletID, body.ToWalletID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sunriseex/test_wallet/internal/ratelimit"
+)
+
+func TestRateLimitMiddleware_PerWallet(t *testing.T) {
+	rules := ratelimit.Rules{Default: ratelimit.Rule{Wallet: ratelimit.Limit{Rate: 0.01, Burst: 2}}}
+
+	var bodies []string
+	r := mux.NewRouter()
+	r.Use(RateLimitMiddleware(ratelimit.NewMemoryStore(), rules))
+	r.HandleFunc("/api/v1/wallet", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}).Methods("POST")
+
+	post := func(walletID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/wallet", strings.NewReader(`{"walletId": "`+walletID+`", "amount": "1"}`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := post("550e8400-e29b-41d4-a716-446655440000"); w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+	}
+	w := post("550e8400-e29b-41d4-a716-446655440000")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "100" {
+		t.Errorf("Expected Retry-After 100, got %q", w.Header().Get("Retry-After"))
+	}
+	if w := post("c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b"); w.Code != http.StatusOK {
+		t.Errorf("Expected other wallet to pass, got %d", w.Code)
+	}
+
+	if len(bodies) != 3 || !strings.Contains(bodies[0], `"amount": "1"`) {
+		t.Errorf("Handler did not receive the original body: %q", bodies)
+	}
+}
+
+func TestRateLimitMiddleware_PerClient(t *testing.T) {
+	rules := ratelimit.Rules{
+		Routes: map[string]ratelimit.Rule{
+			"GET /api/v1/wallets/{walletId}": {Client: ratelimit.Limit{Rate: 0.01, Burst: 1}},
+		},
+	}
+
+	r := mux.NewRouter()
+	r.Use(RateLimitMiddleware(ratelimit.NewMemoryStore(), rules))
+	r.HandleFunc("/api/v1/wallets/{walletId}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	r.HandleFunc("/api/v1/wallets", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+
+	get := func(path, remoteAddr string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("/api/v1/wallets/550e8400-e29b-41d4-a716-446655440000", "10.0.0.1:1234"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if code := get("/api/v1/wallets/c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b", "10.0.0.1:5678"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for the same client, got %d", code)
+	}
+	if code := get("/api/v1/wallets/550e8400-e29b-41d4-a716-446655440000", "10.0.0.2:1234"); code != http.StatusOK {
+		t.Errorf("Expected 200 for another client, got %d", code)
+	}
+	if code := get("/api/v1/wallets", "10.0.0.1:1234"); code != http.StatusOK {
+		t.Errorf("Expected 200 on a route without limits, got %d", code)
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sunriseex/test_wallet/internal/logger"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+// RequestIDMiddleware принимает X-Request-ID клиента (или генерирует новый),
+// возвращает его в ответе и кладет в контекст вместе с walletId из пути,
+// чтобы все строки лога по запросу можно было связать между собой.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := logger.WithRequestID(r.Context(), requestID)
+		if walletID := mux.Vars(r)["walletId"]; walletID != "" {
+			ctx = logger.WithWalletID(ctx, walletID)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID пропускает только короткие печатные идентификаторы, чтобы
+// клиент не мог подмешать в логи переводы строк или мегабайтные значения.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sunriseex/test_wallet/internal/logger"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var requestID, walletID string
+	r := mux.NewRouter()
+	r.Use(RequestIDMiddleware)
+	r.HandleFunc("/api/v1/wallets/{walletId}", func(w http.ResponseWriter, r *http.Request) {
+		requestID = logger.RequestID(r.Context())
+		walletID, _ = logger.Fields(r.Context())[logger.FieldWalletID].(string)
+	})
+
+	testCases := []struct {
+		header string
+		kept   bool
+	}{
+		{"", false},
+		{"client-req.42", true},
+		{"bad\nid", false},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/api/v1/wallets/w-1", nil)
+		if tc.header != "" {
+			req.Header.Set(RequestIDHeader, tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get(RequestIDHeader)
+		if got != requestID {
+			t.Errorf("header %q: response ID %q differs from context ID %q", tc.header, got, requestID)
+		}
+		if tc.kept && got != tc.header {
+			t.Errorf("header %q: Expected client ID to be kept, got %q", tc.header, got)
+		}
+		if _, err := uuid.Parse(got); !tc.kept && err != nil {
+			t.Errorf("header %q: Expected generated UUID, got %q", tc.header, got)
+		}
+		if walletID != "w-1" {
+			t.Errorf("header %q: Expected wallet_id w-1 in context, got %q", tc.header, walletID)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// untracedPaths — служебные эндпоинты, которые опрашиваются постоянно и
+// только засоряли бы трассировку.
+var untracedPaths = map[string]bool{
+	"/metrics": true,
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// TracingMiddleware открывает серверный спан на запрос (с родителем из
+// заголовка traceparent) и добавляет trace_id в поля лога запроса.
+// Спан называется по шаблону маршрута: "POST /api/v1/wallets/{walletId}/holds".
+func TracingMiddleware() func(http.Handler) http.Handler {
+	otelMiddleware := otelhttp.NewMiddleware("http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + routeTemplate(r)
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !untracedPaths[r.URL.Path]
+		}),
+	)
+	return func(next http.Handler) http.Handler {
+		return otelMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+			if span.SpanContext().IsValid() {
+				span.SetAttributes(semconv.HTTPRoute(routeTemplate(r)))
+				r = r.WithContext(logger.WithFields(r.Context(), logrus.Fields{
+					"trace_id": span.SpanContext().TraceID().String(),
+				}))
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t (c);")},
+		"0002_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+		"0001_create_t.up.sql":    {Data: []byte("CREATE TABLE t (c INT);")},
+	}
+
+	migrations, err := load(fsys)
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_t", migrations[0].Name)
+	assert.Empty(t, migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "DROP INDEX i;", migrations[1].Down)
+}
+
+func TestLoad_InvalidFiles(t *testing.T) {
+	testCases := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "Неверное имя файла",
+			fsys: fstest.MapFS{"create_t.sql": {Data: []byte("SELECT 1")}},
+		},
+		{
+			name: "Нет up-скрипта",
+			fsys: fstest.MapFS{"0001_create_t.down.sql": {Data: []byte("DROP TABLE t;")}},
+		},
+		{
+			name: "Разные имена у одной версии",
+			fsys: fstest.MapFS{
+				"0001_create_t.up.sql":   {Data: []byte("CREATE TABLE t (c INT);")},
+				"0001_create_x.down.sql": {Data: []byte("DROP TABLE x;")},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := load(tc.fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrator, err := New(nil)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrator.migrations)
+	for _, migration := range migrator.migrations {
+		assert.NotEmpty(t, migration.Down, "migration %d_%s has no down script", migration.Version, migration.Name)
+	}
+}
+
+func TestUp_AppliesOnlyPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "create_t", Up: "CREATE TABLE t (c INT)"},
+		{Version: 2, Name: "add_index", Up: "CREATE INDEX i ON t (c)"},
+	}}
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).
+		WithArgs(advisoryLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version FROM schema_migrations`)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(int64(1)))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE INDEX i ON t (c)`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`)).
+		WithArgs(int64(2), "add_index").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).
+		WithArgs(advisoryLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
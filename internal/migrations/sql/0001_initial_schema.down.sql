@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS wallet_db;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS wallet_db (
+    wallet_id UUID PRIMARY KEY,
+    balance NUMERIC NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS wallet_id_idx ON wallet_db (wallet_id);
+
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallet_db (wallet_id),
+    amount NUMERIC NOT NULL,
+    operation_type TEXT NOT NULL,
+    balance_after NUMERIC NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE wallet_transactions
+    ADD COLUMN IF NOT EXISTS counterparty_wallet_id UUID REFERENCES wallet_db (wallet_id);
+
+CREATE INDEX IF NOT EXISTS wallet_transactions_wallet_id_created_at_idx
+    ON wallet_transactions (wallet_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    transaction_id UUID REFERENCES wallet_transactions (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
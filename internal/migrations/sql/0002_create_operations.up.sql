@@ -0,0 +1,14 @@
+CREATE TABLE operations (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL,
+    operation_type TEXT NOT NULL,
+    amount NUMERIC NOT NULL,
+    to_wallet_id UUID,
+    idempotency_key TEXT UNIQUE,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'succeeded', 'failed')),
+    error_code TEXT,
+    transaction_id UUID REFERENCES wallet_transactions (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
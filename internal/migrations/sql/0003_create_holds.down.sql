@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS holds;
+ALTER TABLE wallet_db
+    DROP CONSTRAINT IF EXISTS wallet_db_held_balance_check,
+    DROP COLUMN IF EXISTS held_balance;
@@ -0,0 +1,18 @@
+ALTER TABLE wallet_db
+    ADD COLUMN held_balance NUMERIC NOT NULL DEFAULT 0,
+    ADD CONSTRAINT wallet_db_held_balance_check CHECK (held_balance >= 0 AND held_balance <= balance);
+
+CREATE TABLE holds (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallet_db (wallet_id),
+    amount NUMERIC NOT NULL CHECK (amount > 0),
+    captured_amount NUMERIC NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'captured', 'released', 'expired')),
+    transaction_id UUID REFERENCES wallet_transactions (id),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX holds_active_expires_at_idx ON holds (expires_at) WHERE status = 'active';
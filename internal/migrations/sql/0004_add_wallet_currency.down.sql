@@ -0,0 +1,6 @@
+ALTER TABLE operations
+    DROP COLUMN IF EXISTS currency;
+DROP TRIGGER IF EXISTS wallet_db_currency_immutable ON wallet_db;
+DROP FUNCTION IF EXISTS wallet_db_currency_immutable();
+ALTER TABLE wallet_db
+    DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,19 @@
+ALTER TABLE wallet_db
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- Валюта задается при создании кошелька и больше не меняется.
+CREATE FUNCTION wallet_db_currency_immutable() RETURNS trigger AS $$
+BEGIN
+    IF NEW.currency <> OLD.currency THEN
+        RAISE EXCEPTION 'wallet currency is immutable';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER wallet_db_currency_immutable
+    BEFORE UPDATE OF currency ON wallet_db
+    FOR EACH ROW EXECUTE FUNCTION wallet_db_currency_immutable();
+
+ALTER TABLE operations
+    ADD COLUMN currency CHAR(3);
@@ -0,0 +1,24 @@
+CREATE TABLE quotes (
+    id UUID PRIMARY KEY,
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL CHECK (rate > 0),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE conversions (
+    id UUID PRIMARY KEY,
+    quote_id UUID REFERENCES quotes (id),
+    from_wallet_id UUID NOT NULL REFERENCES wallet_db (wallet_id),
+    to_wallet_id UUID NOT NULL REFERENCES wallet_db (wallet_id),
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL,
+    debit_amount NUMERIC NOT NULL,
+    credit_amount NUMERIC NOT NULL,
+    remainder NUMERIC NOT NULL,
+    debit_transaction_id UUID NOT NULL UNIQUE REFERENCES wallet_transactions (id),
+    credit_transaction_id UUID NOT NULL REFERENCES wallet_transactions (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE wallet_db
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,3 @@
+ALTER TABLE wallet_db
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'frozen', 'closed'));
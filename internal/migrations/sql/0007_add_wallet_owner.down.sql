@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS wallet_db_owner_id_created_at_idx;
+
+ALTER TABLE wallet_db
+    DROP COLUMN IF EXISTS labels,
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,8 @@
+ALTER TABLE wallet_db
+    ADD COLUMN owner_id TEXT,
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN labels JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX IF NOT EXISTS wallet_db_owner_id_created_at_idx
+    ON wallet_db (owner_id, created_at)
+    WHERE owner_id IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS wallet_db_balance_wallet_id_idx;
+DROP INDEX IF EXISTS wallet_db_updated_at_wallet_id_idx;
+DROP INDEX IF EXISTS wallet_db_created_at_wallet_id_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS wallet_db_created_at_wallet_id_idx ON wallet_db (created_at, wallet_id);
+CREATE INDEX IF NOT EXISTS wallet_db_updated_at_wallet_id_idx ON wallet_db (updated_at, wallet_id);
+CREATE INDEX IF NOT EXISTS wallet_db_balance_wallet_id_idx ON wallet_db (balance, wallet_id);
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL,
+    auth_method TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    wallet_id TEXT NOT NULL DEFAULT '',
+    balance_before NUMERIC,
+    balance_after NUMERIC,
+    client_ip TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    result TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_wallet_id_id_idx ON audit_log (wallet_id, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_idx ON audit_log (actor, id);
+
+-- Журнал только дописывается: изменение, удаление и очистка запрещены.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only: % is not allowed', TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_update_delete ON audit_log;
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
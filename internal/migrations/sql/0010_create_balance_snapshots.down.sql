@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS balance_snapshot_progress;
+DROP TABLE IF EXISTS wallet_balance_snapshots;
+DROP INDEX IF EXISTS wallet_transactions_created_at_idx;
+ALTER TABLE wallet_transactions
+    ALTER COLUMN created_at SET DEFAULT NOW();
@@ -0,0 +1,23 @@
+-- Время записи транзакции берется в момент вставки, а не в момент начала
+-- транзакции БД. Вставка идет под блокировкой кошелька, поэтому created_at
+-- растет в порядке изменений баланса, и последняя запись не позже момента T
+-- содержит баланс на T.
+ALTER TABLE wallet_transactions
+    ALTER COLUMN created_at SET DEFAULT clock_timestamp();
+
+CREATE INDEX IF NOT EXISTS wallet_transactions_created_at_idx ON wallet_transactions (created_at);
+
+-- Баланс кошелька на конец дня (UTC). Снимок есть только за дни, в которые
+-- баланс менялся; в остальные дни действует последний предыдущий снимок.
+CREATE TABLE IF NOT EXISTS wallet_balance_snapshots (
+    wallet_id UUID NOT NULL REFERENCES wallet_db (wallet_id),
+    day DATE NOT NULL,
+    balance NUMERIC NOT NULL,
+    PRIMARY KEY (wallet_id, day)
+);
+
+-- Последний день, за который снимки сделаны по всем кошелькам.
+CREATE TABLE IF NOT EXISTS balance_snapshot_progress (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    snapshotted_through DATE NOT NULL
+);
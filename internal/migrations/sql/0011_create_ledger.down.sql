@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_postings;
+DROP TABLE IF EXISTS ledger_accounts;
+DROP FUNCTION IF EXISTS ledger_posting_balanced();
+DROP FUNCTION IF EXISTS ledger_append_only();
@@ -0,0 +1,109 @@
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('wallet', 'cash_in', 'cash_out', 'fees', 'fx')),
+    currency TEXT NOT NULL,
+    wallet_id UUID REFERENCES wallet_db (wallet_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (id, currency),
+    CHECK ((kind = 'wallet') = (wallet_id IS NOT NULL))
+);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id UUID PRIMARY KEY,
+    operation_type TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+-- Валюта записи обязана совпадать с валютой счета: внешний ключ по (id, currency).
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    posting_id UUID NOT NULL REFERENCES ledger_postings (id),
+    account_id TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    amount NUMERIC NOT NULL CHECK (amount <> 0),
+    transaction_id UUID REFERENCES wallet_transactions (id),
+    FOREIGN KEY (account_id, currency) REFERENCES ledger_accounts (id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entries_posting_id_idx ON ledger_entries (posting_id);
+CREATE INDEX IF NOT EXISTS ledger_entries_account_id_idx ON ledger_entries (account_id);
+
+-- Сумма записей проводки в каждой валюте равна нулю. Проверка отложена до
+-- коммита, чтобы записи одной проводки можно было вставлять по одной.
+CREATE OR REPLACE FUNCTION ledger_posting_balanced() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM ledger_entries
+        WHERE posting_id = NEW.posting_id
+        GROUP BY currency
+        HAVING SUM(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'ledger posting % is not balanced', NEW.posting_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_balanced ON ledger_entries;
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+    AFTER INSERT ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_posting_balanced();
+
+-- Книга только дописывается: ошибки исправляются новыми проводками.
+CREATE OR REPLACE FUNCTION ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only: % is not allowed', TG_TABLE_NAME, TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_no_update_delete ON ledger_entries;
+CREATE TRIGGER ledger_entries_no_update_delete
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+DROP TRIGGER IF EXISTS ledger_entries_no_truncate ON ledger_entries;
+CREATE TRIGGER ledger_entries_no_truncate
+    BEFORE TRUNCATE ON ledger_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_append_only();
+
+DROP TRIGGER IF EXISTS ledger_postings_no_update_delete ON ledger_postings;
+CREATE TRIGGER ledger_postings_no_update_delete
+    BEFORE UPDATE OR DELETE ON ledger_postings
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+DROP TRIGGER IF EXISTS ledger_postings_no_truncate ON ledger_postings;
+CREATE TRIGGER ledger_postings_no_truncate
+    BEFORE TRUNCATE ON ledger_postings
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_append_only();
+
+-- Перенос существующих балансов: по проводке OPENING_BALANCE на кошелек с
+-- ненулевым балансом против счета внешних зачислений в его валюте.
+INSERT INTO ledger_accounts (id, kind, currency, wallet_id)
+SELECT wallet_id::text, 'wallet', currency, wallet_id
+FROM wallet_db
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO ledger_accounts (id, kind, currency)
+SELECT DISTINCT 'system:cash_in:' || currency, 'cash_in', currency
+FROM wallet_db
+WHERE balance <> 0
+ON CONFLICT (id) DO NOTHING;
+
+CREATE TEMPORARY TABLE ledger_opening ON COMMIT DROP AS
+SELECT gen_random_uuid() AS posting_id, wallet_id, currency, balance
+FROM wallet_db
+WHERE balance <> 0;
+
+INSERT INTO ledger_postings (id, operation_type)
+SELECT posting_id, 'OPENING_BALANCE'
+FROM ledger_opening;
+
+INSERT INTO ledger_entries (posting_id, account_id, currency, amount)
+SELECT posting_id, wallet_id::text, currency, balance
+FROM ledger_opening
+UNION ALL
+SELECT posting_id, 'system:cash_in:' || currency, currency, -balance
+FROM ledger_opening;
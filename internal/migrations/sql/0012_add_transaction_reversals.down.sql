@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS wallet_transactions_reversal_of_idx;
+ALTER TABLE wallet_transactions
+    DROP COLUMN IF EXISTS reversal_of;
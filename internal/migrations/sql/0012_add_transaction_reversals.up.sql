@@ -0,0 +1,7 @@
+-- reversal_of связывает сторно с исходной транзакцией. Журнал остается
+-- append-only: сторнированная часть не хранится, а считается как сумма сторно.
+ALTER TABLE wallet_transactions
+    ADD COLUMN IF NOT EXISTS reversal_of UUID REFERENCES wallet_transactions (id);
+
+CREATE INDEX IF NOT EXISTS wallet_transactions_reversal_of_idx
+    ON wallet_transactions (reversal_of) WHERE reversal_of IS NOT NULL;
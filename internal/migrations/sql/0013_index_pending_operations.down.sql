@@ -0,0 +1 @@
+DROP INDEX IF EXISTS operations_pending_idx;
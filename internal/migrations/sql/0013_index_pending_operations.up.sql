@@ -0,0 +1,3 @@
+-- При старте сервер заново ставит в очередь операции, оставшиеся в pending.
+CREATE INDEX IF NOT EXISTS operations_pending_idx
+    ON operations (id) WHERE status = 'pending';
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AuditResultSuccess — результат успешного действия; у неуспешного в Result
+// код ошибки ответа (INSUFFICIENT_FUNDS, FORBIDDEN и т.п.).
+const AuditResultSuccess = "success"
+
+// AuditEntry — запись журнала аудита. Записи образуют цепочку: PrevHash равен
+// Hash предыдущей записи, а Hash покрывает все поля записи вместе с PrevHash,
+// поэтому изменение или удаление любой записи ломает цепочку.
+type AuditEntry struct {
+	ID         int64  `json:"id"`
+	Actor      string `json:"actor"`
+	AuthMethod string `json:"authMethod,omitempty"`
+	Action     string `json:"action"`
+	WalletID   string `json:"walletId,omitempty"`
+	// BalanceBefore и BalanceAfter заполняются для действий, меняющих баланс.
+	BalanceBefore *decimal.Decimal `json:"balanceBefore,omitempty"`
+	BalanceAfter  *decimal.Decimal `json:"balanceAfter,omitempty"`
+	ClientIP      string           `json:"clientIp"`
+	RequestID     string           `json:"requestId"`
+	Result        string           `json:"result"`
+	CreatedAt     time.Time        `json:"createdAt"`
+	PrevHash      string           `json:"prevHash"`
+	Hash          string           `json:"hash"`
+}
+
+// ComputeHash считает SHA-256 записи (без ID и Hash) в hex. Поля пишутся с
+// префиксом длины, чтобы "ab"+"c" и "a"+"bc" давали разные хеши.
+func (e AuditEntry) ComputeHash() string {
+	h := sha256.New()
+	for _, field := range []string{
+		e.PrevHash,
+		e.Actor,
+		e.AuthMethod,
+		e.Action,
+		e.WalletID,
+		decimalField(e.BalanceBefore),
+		decimalField(e.BalanceAfter),
+		e.ClientIP,
+		e.RequestID,
+		e.Result,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	} {
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func decimalField(d *decimal.Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DateLayout — формат дат дневной истории баланса.
+const DateLayout = "2006-01-02"
+
+// BalanceSnapshot — баланс кошелька на конец дня Day (UTC).
+type BalanceSnapshot struct {
+	WalletID string
+	Day      time.Time
+	Balance  decimal.Decimal
+}
+
+// HistoricalBalance — баланс кошелька на момент At.
+type HistoricalBalance struct {
+	WalletID string          `json:"walletId"`
+	Currency string          `json:"currency"`
+	At       time.Time       `json:"at"`
+	Balance  decimal.Decimal `json:"balance"`
+}
+
+// DailyBalance — баланс на конец дня Date (UTC).
+type DailyBalance struct {
+	Date    string          `json:"date"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+type BalanceSeries struct {
+	WalletID string         `json:"walletId"`
+	Currency string         `json:"currency"`
+	Days     []DailyBalance `json:"days"`
+}
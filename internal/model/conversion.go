@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quote — курс обмена, зафиксированный до ExpiresAt.
+type Quote struct {
+	ID           string          `json:"id"`
+	FromCurrency string          `json:"fromCurrency"`
+	ToCurrency   string          `json:"toCurrency"`
+	Rate         decimal.Decimal `json:"rate"`
+	ExpiresAt    time.Time       `json:"expiresAt"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// Conversion — выполненный обмен между кошельками в разных валютах.
+// Remainder — часть зачисления, отброшенная при округлении до точности
+// валюты получателя.
+type Conversion struct {
+	ID                  string          `json:"id"`
+	QuoteID             string          `json:"quoteId,omitempty"`
+	FromWalletID        string          `json:"fromWalletId"`
+	ToWalletID          string          `json:"toWalletId"`
+	FromCurrency        string          `json:"fromCurrency"`
+	ToCurrency          string          `json:"toCurrency"`
+	Rate                decimal.Decimal `json:"rate"`
+	DebitAmount         decimal.Decimal `json:"debitAmount"`
+	CreditAmount        decimal.Decimal `json:"creditAmount"`
+	Remainder           decimal.Decimal `json:"remainder"`
+	DebitTransactionID  string          `json:"debitTransactionId"`
+	CreditTransactionID string          `json:"creditTransactionId"`
+	CreatedAt           time.Time       `json:"createdAt"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+// Hold — резерв средств на кошельке. Пока холд активен, его сумма входит в
+// HeldBalance кошелька и недоступна для списания.
+type Hold struct {
+	ID             string          `json:"id"`
+	WalletID       string          `json:"walletId"`
+	Amount         decimal.Decimal `json:"amount"`
+	CapturedAmount decimal.Decimal `json:"capturedAmount"`
+	Status         string          `json:"status"`
+	TransactionID  string          `json:"transactionId,omitempty"`
+	ExpiresAt      time.Time       `json:"expiresAt"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
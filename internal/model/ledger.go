@@ -0,0 +1,109 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Виды счетов главной книги. Кошелек клиента — счет вида wallet с ID кошелька,
+// системные счета заводятся по одному на вид и валюту.
+const (
+	AccountKindWallet = "wallet"
+	// AccountKindCashIn — источник внешних зачислений (пополнений).
+	AccountKindCashIn = "cash_in"
+	// AccountKindCashOut — получатель внешних списаний (выводов и списаний холдов).
+	AccountKindCashOut = "cash_out"
+	// AccountKindFees — доход от комиссий. Операций с комиссией пока нет.
+	AccountKindFees = "fees"
+	// AccountKindFX — позиция по валюте при обмене: обмен проводится двумя
+	// проводками, каждая в своей валюте.
+	AccountKindFX = "fx"
+)
+
+// OperationOpeningBalance — проводка, которой миграция перенесла в книгу
+// балансы кошельков, существовавших до ее появления.
+const OperationOpeningBalance = "OPENING_BALANCE"
+
+type LedgerAccount struct {
+	ID       string `json:"accountId"`
+	Kind     string `json:"kind"`
+	Currency string `json:"currency"`
+	WalletID string `json:"walletId,omitempty"`
+}
+
+func WalletAccount(walletID, currency string) LedgerAccount {
+	return LedgerAccount{ID: walletID, Kind: AccountKindWallet, Currency: currency, WalletID: walletID}
+}
+
+func SystemAccount(kind, currency string) LedgerAccount {
+	return LedgerAccount{ID: "system:" + kind + ":" + currency, Kind: kind, Currency: currency}
+}
+
+// LedgerEntry — запись проводки по счету. Положительная сумма — дебет
+// (увеличение баланса кошелька), отрицательная — кредит.
+type LedgerEntry struct {
+	Account       LedgerAccount   `json:"account"`
+	Amount        decimal.Decimal `json:"amount"`
+	TransactionID string          `json:"transactionId,omitempty"`
+}
+
+// Posting — проводка операции. Сумма ее записей в каждой валюте равна нулю.
+type Posting struct {
+	ID            string        `json:"id"`
+	OperationType string        `json:"operationType"`
+	Entries       []LedgerEntry `json:"entries"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
+
+// Balanced сообщает, что у проводки есть записи и в каждой валюте они в сумме
+// дают ноль.
+func (p Posting) Balanced() bool {
+	if len(p.Entries) == 0 {
+		return false
+	}
+	sums := make(map[string]decimal.Decimal)
+	for _, e := range p.Entries {
+		sums[e.Account.Currency] = sums[e.Account.Currency].Add(e.Amount)
+	}
+	for _, sum := range sums {
+		if !sum.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// LedgerClassBalance — суммарный баланс счетов одного вида в одной валюте.
+type LedgerClassBalance struct {
+	Kind     string          `json:"kind"`
+	Currency string          `json:"currency"`
+	Balance  decimal.Decimal `json:"balance"`
+}
+
+// CurrencyTrialBalance — итог оборотно-сальдовой ведомости по валюте:
+// положительные балансы (дебет) и модуль отрицательных (кредит).
+type CurrencyTrialBalance struct {
+	Currency string          `json:"currency"`
+	Debits   decimal.Decimal `json:"debits"`
+	Credits  decimal.Decimal `json:"credits"`
+	Balanced bool            `json:"balanced"`
+}
+
+// WalletMismatch — кошелек, баланс которого в wallet_db расходится с суммой
+// записей его счета в книге.
+type WalletMismatch struct {
+	WalletID      string          `json:"walletId"`
+	WalletBalance decimal.Decimal `json:"walletBalance"`
+	LedgerBalance decimal.Decimal `json:"ledgerBalance"`
+}
+
+// TrialBalance — проверка книги: Balanced — дебет равен кредиту во всех
+// валютах, Reconciled — балансы кошельков совпадают с книгой.
+type TrialBalance struct {
+	Balanced         bool                   `json:"balanced"`
+	Reconciled       bool                   `json:"reconciled"`
+	Currencies       []CurrencyTrialBalance `json:"currencies"`
+	Accounts         []LedgerClassBalance   `json:"accounts"`
+	WalletMismatches []WalletMismatch       `json:"walletMismatches"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	OperationStatusPending   = "pending"
+	OperationStatusSucceeded = "succeeded"
+	OperationStatusFailed    = "failed"
+)
+
+// Operation — асинхронно выполняемая операция над балансом.
+type Operation struct {
+	ID             string          `json:"id"`
+	WalletID       string          `json:"walletId"`
+	OperationType  string          `json:"operationType"`
+	Amount         decimal.Decimal `json:"amount"`
+	Currency       string          `json:"currency,omitempty"`
+	ToWalletID     string          `json:"toWalletId,omitempty"`
+	IdempotencyKey string          `json:"-"`
+	Status         string          `json:"status"`
+	ErrorCode      string          `json:"errorCode,omitempty"`
+	TransactionID  string          `json:"transactionId,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	UpdatedAt      time.Time       `json:"updatedAt"`
+}
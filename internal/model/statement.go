@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StatementHeader — начало выписки: период [From, To) и баланс на момент From.
+type StatementHeader struct {
+	WalletID       string          `json:"walletId"`
+	Currency       string          `json:"currency"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance decimal.Decimal `json:"openingBalance"`
+}
+
+// StatementSummary — итог выписки: баланс на момент To, число операций и
+// суммы зачислений и списаний (списания — по модулю).
+type StatementSummary struct {
+	ClosingBalance decimal.Decimal `json:"closingBalance"`
+	OperationCount int             `json:"operationCount"`
+	TotalCredits   decimal.Decimal `json:"totalCredits"`
+	TotalDebits    decimal.Decimal `json:"totalDebits"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	OperationDeposit    = "DEPOSIT"
+	OperationWithdraw   = "WITHDRAW"
+	OperationTransfer   = "TRANSFER"
+	OperationCapture    = "CAPTURE"
+	OperationConversion = "CONVERSION"
+	// OperationReversal — сторно (полное или частичное) пополнения или
+	// списания. Сумма противоположна по знаку исходной.
+	OperationReversal = "REVERSAL"
+)
+
+type Transaction struct {
+	ID            string          `json:"id"`
+	WalletID      string          `json:"walletId"`
+	Amount        decimal.Decimal `json:"amount"`
+	OperationType string          `json:"operationType"`
+	BalanceAfter  decimal.Decimal `json:"balanceAfter"`
+	// CounterpartyWalletID заполняется для переводов: кошелек получателя
+	// у списания и кошелек отправителя у зачисления.
+	CounterpartyWalletID string `json:"counterpartyWalletId,omitempty"`
+	// ReversalOf — ID исходной транзакции у сторно.
+	ReversalOf string `json:"reversalOf,omitempty"`
+	// ReversedAmount — сколько суммы транзакции уже сторнировано (по модулю).
+	ReversedAmount decimal.Decimal `json:"reversedAmount,omitzero"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// Reversible сообщает, можно ли сторнировать транзакцию.
+func (t Transaction) Reversible() bool {
+	return t.OperationType == OperationDeposit || t.OperationType == OperationWithdraw
+}
+
+// Unreversed — часть суммы транзакции (по модулю), которую еще можно сторнировать.
+func (t Transaction) Unreversed() decimal.Decimal {
+	return t.Amount.Abs().Sub(t.ReversedAmount)
+}
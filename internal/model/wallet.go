@@ -6,9 +6,32 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+const (
+	WalletStatusActive = "active"
+	// WalletStatusFrozen — кошелек принимает зачисления, но не списания.
+	WalletStatusFrozen = "frozen"
+	// WalletStatusClosed — кошелек не принимает никаких операций.
+	WalletStatusClosed = "closed"
+)
+
 type Wallet struct {
-	WalletID  string          `json:"walletId"`
-	Balance   decimal.Decimal `json:"balance"`
-	CreatedAt time.Time       `json:"createdAt"`
-	UpdatedAt time.Time       `json:"updatedAt"`
+	WalletID string          `json:"walletId"`
+	Balance  decimal.Decimal `json:"balance"`
+	Currency string          `json:"currency"`
+	Status   string          `json:"status"`
+	// HeldBalance — сумма активных холдов, она входит в Balance, но не
+	// может быть списана.
+	HeldBalance decimal.Decimal `json:"heldBalance"`
+	// OwnerID — внешний идентификатор владельца (пользователя в системе
+	// клиента). У одного владельца может быть несколько кошельков.
+	OwnerID   string         `json:"ownerId,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Labels    []string       `json:"labels,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Available возвращает сумму, доступную для списания.
+func (w Wallet) Available() decimal.Decimal {
+	return w.Balance.Sub(w.HeldBalance)
 }
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		result, err := store.Take(ctx, "wallet", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, err := store.Take(ctx, "wallet", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	other, err := store.Take(ctx, "other", limit)
+	require.NoError(t, err)
+	assert.True(t, other.Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = store.Take(ctx, "wallet", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	unlimited, err := store.Take(ctx, "wallet", Limit{})
+	require.NoError(t, err)
+	assert.True(t, unlimited.Allowed)
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := store.Take(ctx, "fast", Limit{Rate: 10, Burst: 1})
+	require.NoError(t, err)
+	_, err = store.Take(ctx, "slow", Limit{Rate: 0.001, Burst: 1})
+	require.NoError(t, err)
+
+	now = now.Add(2 * sweepInterval)
+	_, err = store.Take(ctx, "trigger", Limit{Rate: 10, Burst: 1})
+	require.NoError(t, err)
+
+	assert.NotContains(t, store.buckets, "fast")
+	assert.Contains(t, store.buckets, "slow")
+}
+
+func TestRules_For(t *testing.T) {
+	rules := Rules{
+		Default: Rule{Client: Limit{Rate: 100, Burst: 200}},
+		Routes:  map[string]Rule{"POST /api/v1/wallet": {Wallet: Limit{Rate: 10, Burst: 20}}},
+	}
+
+	rule, space := rules.For("POST", "/api/v1/wallet")
+	assert.Equal(t, "POST /api/v1/wallet", space)
+	assert.Equal(t, 10.0, rule.Wallet.Rate)
+
+	rule, space = rules.For("GET", "/api/v1/wallets/{walletId}")
+	assert.Equal(t, "default", space)
+	assert.Equal(t, 100.0, rule.Client.Rate)
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Limit — параметры token bucket: Rate токенов в секунду, не больше Burst
+// накопленных. Нулевой Rate отключает ограничение.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0
+}
+
+type Result struct {
+	Allowed bool
+	// RetryAfter — через сколько появится следующий токен, если запрос отклонен.
+	RetryAfter time.Duration
+}
+
+// Store хранит состояние бакетов. MemoryStore работает в пределах процесса;
+// для нескольких реплик нужна реализация поверх общего хранилища.
+type Store interface {
+	// Take списывает токен из бакета key и сообщает, разрешен ли запрос.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rule — лимиты маршрута: на клиента (API-ключ, пользователь или IP) и на
+// кошелек, к которому обращается запрос.
+type Rule struct {
+	Client Limit `json:"client"`
+	Wallet Limit `json:"wallet"`
+}
+
+// Rules — правило по умолчанию и переопределения для маршрутов. Ключ
+// маршрута — метод и шаблон пути: "POST /api/v1/wallet".
+type Rules struct {
+	Default Rule            `json:"default"`
+	Routes  map[string]Rule `json:"routes,omitempty"`
+}
+
+// For возвращает правило маршрута и имя его пространства бакетов: у
+// маршрута с собственным правилом бакеты свои, остальные делят общие.
+func (r Rules) For(method, route string) (Rule, string) {
+	key := method + " " + route
+	if rule, ok := r.Routes[key]; ok {
+		return rule, key
+	}
+	return r.Default, "default"
+}
+
+// LoadRules читает правила из JSON-файла, дополняя defaults.
+func LoadRules(path string, defaults Rule) (Rules, error) {
+	rules := Rules{Default: defaults}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return Rules{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for route, rule := range rules.Routes {
+		for _, limit := range []Limit{rule.Client, rule.Wallet} {
+			if limit.Enabled() && limit.Burst < 1 {
+				return Rules{}, fmt.Errorf("%s: burst must be at least 1", route)
+			}
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,62 @@
+package rates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// Static отдает курсы из заранее заданной таблицы и не ходит в сеть.
+// Ключ таблицы — пара вида "USD/RUB": сколько RUB дают за 1 USD. Обратный
+// курс вычисляется, если он не задан явно.
+type Static struct {
+	rates map[string]decimal.Decimal
+}
+
+func NewStatic(rates map[string]decimal.Decimal) (*Static, error) {
+	normalized := make(map[string]decimal.Decimal, len(rates))
+	for pair, rate := range rates {
+		from, to, ok := strings.Cut(strings.ToUpper(pair), "/")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("rate for %s must be positive", pair)
+		}
+		normalized[from+"/"+to] = rate
+	}
+	return &Static{rates: normalized}, nil
+}
+
+// LoadFile читает таблицу курсов из JSON-файла вида {"USD/RUB": "92.50"}.
+func LoadFile(path string) (*Static, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[string]decimal.Decimal
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewStatic(rates)
+}
+
+func (s *Static) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	if rate, ok := s.rates[from+"/"+to]; ok {
+		return rate, nil
+	}
+	if rate, ok := s.rates[to+"/"+from]; ok {
+		return decimal.NewFromInt(1).Div(rate), nil
+	}
+	return decimal.Decimal{}, fmt.Errorf("%w: %s/%s", ErrRateNotFound, from, to)
+}
@@ -0,0 +1,50 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatic_Rate(t *testing.T) {
+	provider, err := NewStatic(map[string]decimal.Decimal{
+		"usd/rub": decimal.NewFromInt(80),
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	rate, err := provider.Rate(ctx, "USD", "RUB")
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(80)))
+
+	rate, err = provider.Rate(ctx, "RUB", "USD")
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.RequireFromString("0.0125")))
+
+	_, err = provider.Rate(ctx, "USD", "JPY")
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+}
+
+func TestNewStatic_Invalid(t *testing.T) {
+	_, err := NewStatic(map[string]decimal.Decimal{"USDRUB": decimal.NewFromInt(1)})
+	assert.Error(t, err)
+	_, err = NewStatic(map[string]decimal.Decimal{"USD/RUB": decimal.Zero})
+	assert.Error(t, err)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"EUR/USD": "1.08"}`), 0o600))
+
+	provider, err := LoadFile(path)
+	require.NoError(t, err)
+	rate, err := provider.Rate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.RequireFromString("1.08")))
+}
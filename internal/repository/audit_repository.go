@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// AuditFilter — условия выборки ListAudit. Пустые поля не ограничивают
+// выборку; From включается, To — нет. Записи идут по возрастанию ID.
+type AuditFilter struct {
+	WalletID string
+	Actor    string
+	Action   string
+	From     time.Time
+	To       time.Time
+	// AfterID — ID последней записи предыдущей страницы.
+	AfterID int64
+	Limit   int
+}
+
+// AuditRepository — журнал аудита, который можно только дописывать.
+type AuditRepository interface {
+	// AppendAudit дописывает запись в конец цепочки: проставляет CreatedAt,
+	// PrevHash, Hash и ID. Записи добавляются строго по одной.
+	AppendAudit(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error)
+	ListAudit(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error)
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// MemoryRepository хранит данные в памяти процесса. Пишущие транзакции
+// выполняются строго по одной (аналог блокировки строк FOR UPDATE), чтения
+// вне транзакций видят только закоммиченные данные.
+type MemoryRepository struct {
+	writer chan struct{}
+
+	mu           sync.RWMutex
+	wallets      map[string]model.Wallet
+	transactions []model.Transaction
+	idempotency  map[string]IdempotencyRecord
+	operations   map[string]model.Operation
+	holds        map[string]model.Hold
+	quotes       map[string]model.Quote
+	conversions  []model.Conversion
+	postings     []model.Posting
+	audit        []model.AuditEntry
+
+	snapshots          []model.BalanceSnapshot
+	snapshottedThrough time.Time
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		writer:      make(chan struct{}, 1),
+		wallets:     make(map[string]model.Wallet),
+		idempotency: make(map[string]IdempotencyRecord),
+		operations:  make(map[string]model.Operation),
+		holds:       make(map[string]model.Hold),
+		quotes:      make(map[string]model.Quote),
+	}
+}
+
+func (r *MemoryRepository) BeginTx(ctx context.Context) (WalletTx, error) {
+	select {
+	case r.writer <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &memoryTx{
+		repo:        r,
+		wallets:     make(map[string]model.Wallet),
+		idempotency: make(map[string]IdempotencyRecord),
+		holds:       make(map[string]model.Hold),
+	}, nil
+}
+
+func (r *MemoryRepository) GetWallet(ctx context.Context, walletID string) (model.Wallet, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wallet, ok := r.wallets[walletID]
+	if !ok {
+		return model.Wallet{}, ErrNotFound
+	}
+	return wallet, nil
+}
+
+func (r *MemoryRepository) ListTransactions(ctx context.Context, walletID string, limit, offset int) ([]model.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []model.Transaction
+	for _, t := range r.transactions {
+		if t.WalletID == walletID {
+			matched = append(matched, t)
+		}
+	}
+	// Сторно всегда относится к тому же кошельку, что и исходная транзакция.
+	setReversedAmounts(matched, matched)
+	sort.SliceStable(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID < matched[j].ID
+	})
+
+	transactions := make([]model.Transaction, 0, limit)
+	for i := offset; i < len(matched) && len(transactions) < limit; i++ {
+		transactions = append(transactions, matched[i])
+	}
+	return transactions, nil
+}
+
+func (r *MemoryRepository) StreamTransactions(ctx context.Context, walletID string, from, to time.Time, fn func(model.Transaction) error) error {
+	r.mu.RLock()
+	var matched, wallet []model.Transaction
+	for _, t := range r.transactions {
+		if t.WalletID != walletID {
+			continue
+		}
+		wallet = append(wallet, t)
+		if !t.CreatedAt.Before(from) && t.CreatedAt.Before(to) {
+			matched = append(matched, t)
+		}
+	}
+	setReversedAmounts(matched, wallet)
+	r.mu.RUnlock()
+
+	for _, t := range matched {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type memoryTx struct {
+	repo *MemoryRepository
+	done bool
+
+	wallets      map[string]model.Wallet
+	transactions []model.Transaction
+	idempotency  map[string]IdempotencyRecord
+	holds        map[string]model.Hold
+	conversions  []model.Conversion
+	postings     []model.Posting
+}
+
+func (t *memoryTx) Commit() error {
+	if t.done {
+		return ErrTxDone
+	}
+	t.done = true
+
+	t.repo.mu.Lock()
+	for id, wallet := range t.wallets {
+		t.repo.wallets[id] = wallet
+	}
+	t.repo.transactions = append(t.repo.transactions, t.transactions...)
+	for key, record := range t.idempotency {
+		t.repo.idempotency[key] = record
+	}
+	for id, hold := range t.holds {
+		t.repo.holds[id] = hold
+	}
+	t.repo.conversions = append(t.repo.conversions, t.conversions...)
+	t.repo.postings = append(t.repo.postings, t.postings...)
+	t.repo.mu.Unlock()
+
+	<-t.repo.writer
+	return nil
+}
+
+func (t *memoryTx) Rollback() error {
+	if t.done {
+		return ErrTxDone
+	}
+	t.done = true
+	<-t.repo.writer
+	return nil
+}
+
+func (t *memoryTx) LockWallet(ctx context.Context, walletID string) (model.Wallet, error) {
+	if t.done {
+		return model.Wallet{}, ErrTxDone
+	}
+	if wallet, ok := t.wallets[walletID]; ok {
+		return wallet, nil
+	}
+	return t.repo.GetWallet(ctx, walletID)
+}
+
+func (t *memoryTx) InsertWallet(ctx context.Context, wallet model.Wallet) (model.Wallet, error) {
+	if t.done {
+		return model.Wallet{}, ErrTxDone
+	}
+	if _, err := t.LockWallet(ctx, wallet.WalletID); err == nil {
+		return model.Wallet{}, ErrDuplicate
+	}
+	now := time.Now().UTC()
+	wallet.CreatedAt = now
+	wallet.UpdatedAt = now
+	t.wallets[wallet.WalletID] = wallet
+	return wallet, nil
+}
+
+func (t *memoryTx) UpdateWallet(ctx context.Context, wallet model.Wallet) error {
+	current, err := t.LockWallet(ctx, wallet.WalletID)
+	if err != nil {
+		return err
+	}
+	current.Status = wallet.Status
+	current.OwnerID = wallet.OwnerID
+	current.Metadata = wallet.Metadata
+	current.Labels = wallet.Labels
+	current.UpdatedAt = time.Now().UTC()
+	t.wallets[wallet.WalletID] = current
+	return nil
+}
+
+func (t *memoryTx) UpdateBalance(ctx context.Context, walletID string, balance decimal.Decimal) error {
+	wallet, err := t.LockWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	wallet.Balance = balance
+	wallet.UpdatedAt = time.Now().UTC()
+	t.wallets[walletID] = wallet
+	return nil
+}
+
+func (t *memoryTx) InsertTransaction(ctx context.Context, transaction model.Transaction) (model.Transaction, error) {
+	if t.done {
+		return model.Transaction{}, ErrTxDone
+	}
+	transaction.ID = uuid.NewString()
+	transaction.CreatedAt = time.Now().UTC()
+	t.transactions = append(t.transactions, transaction)
+	return transaction, nil
+}
+
+func (t *memoryTx) GetTransaction(ctx context.Context, transactionID string) (model.Transaction, error) {
+	if t.done {
+		return model.Transaction{}, ErrTxDone
+	}
+	t.repo.mu.RLock()
+	defer t.repo.mu.RUnlock()
+
+	all := append(t.repo.transactions[:len(t.repo.transactions):len(t.repo.transactions)], t.transactions...)
+	for _, transaction := range all {
+		if transaction.ID == transactionID {
+			found := []model.Transaction{transaction}
+			setReversedAmounts(found, all)
+			return found[0], nil
+		}
+	}
+	return model.Transaction{}, ErrNotFound
+}
+
+// setReversedAmounts заполняет ReversedAmount транзакций суммой сторно из all,
+// как подзапрос в transactionColumns у PostgreSQL.
+func setReversedAmounts(transactions, all []model.Transaction) {
+	reversed := make(map[string]decimal.Decimal)
+	for _, t := range all {
+		if t.ReversalOf != "" {
+			reversed[t.ReversalOf] = reversed[t.ReversalOf].Add(t.Amount.Abs())
+		}
+	}
+	for i := range transactions {
+		transactions[i].ReversedAmount = reversed[transactions[i].ID]
+	}
+}
+
+func (t *memoryTx) ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (IdempotencyRecord, bool, error) {
+	if t.done {
+		return IdempotencyRecord{}, false, ErrTxDone
+	}
+	if record, ok := t.idempotency[key]; ok {
+		return record, false, nil
+	}
+
+	t.repo.mu.RLock()
+	record, ok := t.repo.idempotency[key]
+	t.repo.mu.RUnlock()
+	if ok {
+		return record, false, nil
+	}
+
+	record = IdempotencyRecord{Key: key, RequestHash: requestHash}
+	t.idempotency[key] = record
+	return record, true, nil
+}
+
+func (t *memoryTx) CompleteIdempotencyKey(ctx context.Context, key, transactionID string) error {
+	if t.done {
+		return ErrTxDone
+	}
+	record, ok := t.idempotency[key]
+	if !ok {
+		return ErrNotFound
+	}
+	record.TransactionID = transactionID
+	t.idempotency[key] = record
+	return nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (r *MemoryRepository) AppendAudit(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = int64(len(r.audit)) + 1
+	entry.PrevHash = ""
+	if len(r.audit) > 0 {
+		entry.PrevHash = r.audit[len(r.audit)-1].Hash
+	}
+	entry.CreatedAt = time.Now().UTC()
+	entry.Hash = entry.ComputeHash()
+	r.audit = append(r.audit, entry)
+	return entry, nil
+}
+
+func (r *MemoryRepository) ListAudit(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]model.AuditEntry, 0, filter.Limit)
+	for _, e := range r.audit {
+		if len(entries) == filter.Limit {
+			break
+		}
+		if e.ID <= filter.AfterID ||
+			(filter.WalletID != "" && e.WalletID != filter.WalletID) ||
+			(filter.Actor != "" && e.Actor != filter.Actor) ||
+			(filter.Action != "" && e.Action != filter.Action) ||
+			(!filter.From.IsZero() && e.CreatedAt.Before(filter.From)) ||
+			(!filter.To.IsZero() && !e.CreatedAt.Before(filter.To)) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (r *MemoryRepository) BalanceAt(ctx context.Context, walletID string, at time.Time) (decimal.Decimal, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Транзакции хранятся в порядке коммита, а он совпадает с порядком created_at.
+	for i := len(r.transactions) - 1; i >= 0; i-- {
+		t := r.transactions[i]
+		if t.WalletID == walletID && !t.CreatedAt.After(at) {
+			return t.BalanceAfter, true, nil
+		}
+	}
+	return decimal.Zero, false, nil
+}
+
+func (r *MemoryRepository) OpeningBalance(ctx context.Context, walletID string) (decimal.Decimal, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.transactions {
+		if t.WalletID == walletID {
+			return t.BalanceAfter.Sub(t.Amount), true, nil
+		}
+	}
+	return decimal.Zero, false, nil
+}
+
+func (r *MemoryRepository) ListBalanceSnapshots(ctx context.Context, walletID string, from, to time.Time) ([]model.BalanceSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var snapshots []model.BalanceSnapshot
+	var previous *model.BalanceSnapshot
+	for i, s := range r.snapshots {
+		if s.WalletID != walletID {
+			continue
+		}
+		switch {
+		case s.Day.Before(from):
+			if previous == nil || s.Day.After(previous.Day) {
+				previous = &r.snapshots[i]
+			}
+		case !s.Day.After(to):
+			snapshots = append(snapshots, s)
+		}
+	}
+	if previous != nil {
+		snapshots = append(snapshots, *previous)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Day.Before(snapshots[j].Day)
+	})
+	return snapshots, nil
+}
+
+func (r *MemoryRepository) SnapshotHorizon(ctx context.Context) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.snapshottedThrough, nil
+}
+
+func (r *MemoryRepository) SnapshotBalances(ctx context.Context, through time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.snapshottedThrough.IsZero() && !through.After(r.snapshottedThrough) {
+		return 0, nil
+	}
+	end := through.AddDate(0, 0, 1)
+
+	type key struct {
+		walletID string
+		day      time.Time
+	}
+	closing := make(map[key]decimal.Decimal)
+	var order []key
+	for _, t := range r.transactions {
+		if !t.CreatedAt.Before(end) {
+			continue
+		}
+		day := t.CreatedAt.UTC().Truncate(24 * time.Hour)
+		if !r.snapshottedThrough.IsZero() && !day.After(r.snapshottedThrough) {
+			continue
+		}
+		k := key{walletID: t.WalletID, day: day}
+		if _, ok := closing[k]; !ok {
+			order = append(order, k)
+		}
+		closing[k] = t.BalanceAfter
+	}
+
+	for _, k := range order {
+		r.snapshots = append(r.snapshots, model.BalanceSnapshot{WalletID: k.walletID, Day: k.day, Balance: closing[k]})
+	}
+	r.snapshottedThrough = through
+	return len(order), nil
+}
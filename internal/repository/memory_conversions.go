@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (r *MemoryRepository) InsertQuote(ctx context.Context, quote model.Quote) (model.Quote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quote.CreatedAt = time.Now().UTC()
+	r.quotes[quote.ID] = quote
+	return quote, nil
+}
+
+func (r *MemoryRepository) GetQuote(ctx context.Context, quoteID string) (model.Quote, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	quote, ok := r.quotes[quoteID]
+	if !ok {
+		return model.Quote{}, ErrNotFound
+	}
+	return quote, nil
+}
+
+func (t *memoryTx) InsertConversion(ctx context.Context, c model.Conversion) (model.Conversion, error) {
+	if t.done {
+		return model.Conversion{}, ErrTxDone
+	}
+	c.CreatedAt = time.Now().UTC()
+	t.conversions = append(t.conversions, c)
+	return c, nil
+}
+
+func (t *memoryTx) GetConversionByTransaction(ctx context.Context, debitTransactionID string) (model.Conversion, error) {
+	if t.done {
+		return model.Conversion{}, ErrTxDone
+	}
+	for _, c := range t.conversions {
+		if c.DebitTransactionID == debitTransactionID {
+			return c, nil
+		}
+	}
+
+	t.repo.mu.RLock()
+	defer t.repo.mu.RUnlock()
+	for _, c := range t.repo.conversions {
+		if c.DebitTransactionID == debitTransactionID {
+			return c, nil
+		}
+	}
+	return model.Conversion{}, ErrNotFound
+}
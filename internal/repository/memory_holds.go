@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (r *MemoryRepository) GetHold(ctx context.Context, holdID string) (model.Hold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hold, ok := r.holds[holdID]
+	if !ok {
+		return model.Hold{}, ErrNotFound
+	}
+	return hold, nil
+}
+
+func (r *MemoryRepository) ListExpiredHolds(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var expired []model.Hold
+	for _, hold := range r.holds {
+		if hold.Status == model.HoldStatusActive && !hold.ExpiresAt.After(before) {
+			expired = append(expired, hold)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+
+	ids := make([]string, 0, limit)
+	for i := 0; i < len(expired) && len(ids) < limit; i++ {
+		ids = append(ids, expired[i].ID)
+	}
+	return ids, nil
+}
+
+func (t *memoryTx) UpdateHeldBalance(ctx context.Context, walletID string, held decimal.Decimal) error {
+	wallet, err := t.LockWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	wallet.HeldBalance = held
+	wallet.UpdatedAt = time.Now().UTC()
+	t.wallets[walletID] = wallet
+	return nil
+}
+
+func (t *memoryTx) InsertHold(ctx context.Context, hold model.Hold) (model.Hold, error) {
+	if t.done {
+		return model.Hold{}, ErrTxDone
+	}
+	now := time.Now().UTC()
+	hold.CreatedAt = now
+	hold.UpdatedAt = now
+	t.holds[hold.ID] = hold
+	return hold, nil
+}
+
+func (t *memoryTx) LockHold(ctx context.Context, holdID string) (model.Hold, error) {
+	if t.done {
+		return model.Hold{}, ErrTxDone
+	}
+	if hold, ok := t.holds[holdID]; ok {
+		return hold, nil
+	}
+	return t.repo.GetHold(ctx, holdID)
+}
+
+func (t *memoryTx) UpdateHold(ctx context.Context, hold model.Hold) (model.Hold, error) {
+	stored, err := t.LockHold(ctx, hold.ID)
+	if err != nil {
+		return model.Hold{}, err
+	}
+	stored.Status = hold.Status
+	stored.CapturedAmount = hold.CapturedAmount
+	stored.TransactionID = hold.TransactionID
+	stored.UpdatedAt = time.Now().UTC()
+	t.holds[hold.ID] = stored
+	return stored, nil
+}
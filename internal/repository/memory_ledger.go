@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (t *memoryTx) InsertPosting(ctx context.Context, posting model.Posting) (model.Posting, error) {
+	if t.done {
+		return model.Posting{}, ErrTxDone
+	}
+	if !posting.Balanced() {
+		return model.Posting{}, ErrUnbalancedPosting
+	}
+	posting.CreatedAt = time.Now().UTC()
+	t.postings = append(t.postings, posting)
+	return posting, nil
+}
+
+func (r *MemoryRepository) TrialBalance(ctx context.Context, mismatchLimit int) ([]model.LedgerClassBalance, []model.WalletMismatch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type class struct{ kind, currency string }
+	totals := make(map[class]decimal.Decimal)
+	accounts := make(map[string]decimal.Decimal)
+	for _, p := range r.postings {
+		for _, e := range p.Entries {
+			c := class{kind: e.Account.Kind, currency: e.Account.Currency}
+			totals[c] = totals[c].Add(e.Amount)
+			accounts[e.Account.ID] = accounts[e.Account.ID].Add(e.Amount)
+		}
+	}
+
+	classes := make([]model.LedgerClassBalance, 0, len(totals))
+	for c, balance := range totals {
+		classes = append(classes, model.LedgerClassBalance{Kind: c.kind, Currency: c.currency, Balance: balance})
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].Currency != classes[j].Currency {
+			return classes[i].Currency < classes[j].Currency
+		}
+		return classes[i].Kind < classes[j].Kind
+	})
+
+	var mismatches []model.WalletMismatch
+	for id, wallet := range r.wallets {
+		if ledger := accounts[id]; !wallet.Balance.Equal(ledger) {
+			mismatches = append(mismatches, model.WalletMismatch{WalletID: id, WalletBalance: wallet.Balance, LedgerBalance: ledger})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].WalletID < mismatches[j].WalletID
+	})
+	if len(mismatches) > mismatchLimit {
+		mismatches = mismatches[:mismatchLimit]
+	}
+	return classes, mismatches, nil
+}
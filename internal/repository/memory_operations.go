@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (r *MemoryRepository) InsertOperation(ctx context.Context, op model.Operation) (model.Operation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.operations[op.ID]; ok {
+		return model.Operation{}, ErrDuplicate
+	}
+	if op.IdempotencyKey != "" {
+		for _, existing := range r.operations {
+			if existing.IdempotencyKey == op.IdempotencyKey {
+				return model.Operation{}, ErrDuplicate
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	op.CreatedAt = now
+	op.UpdatedAt = now
+	r.operations[op.ID] = op
+	return op, nil
+}
+
+func (r *MemoryRepository) GetOperation(ctx context.Context, operationID string) (model.Operation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	op, ok := r.operations[operationID]
+	if !ok {
+		return model.Operation{}, ErrNotFound
+	}
+	return op, nil
+}
+
+func (r *MemoryRepository) GetOperationByIdempotencyKey(ctx context.Context, key string) (model.Operation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, op := range r.operations {
+		if op.IdempotencyKey == key {
+			return op, nil
+		}
+	}
+	return model.Operation{}, ErrNotFound
+}
+
+func (r *MemoryRepository) ListPendingOperations(ctx context.Context, createdBefore time.Time, afterID string, limit int) ([]model.Operation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ops []model.Operation
+	for _, op := range r.operations {
+		if op.Status == model.OperationStatusPending && op.CreatedAt.Before(createdBefore) && op.ID > afterID {
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].ID < ops[j].ID })
+	if len(ops) > limit {
+		ops = ops[:limit]
+	}
+	return ops, nil
+}
+
+func (r *MemoryRepository) UpdateOperation(ctx context.Context, op model.Operation) (model.Operation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.operations[op.ID]
+	if !ok {
+		return model.Operation{}, ErrNotFound
+	}
+	stored.Status = op.Status
+	stored.ErrorCode = op.ErrorCode
+	stored.TransactionID = op.TransactionID
+	stored.UpdatedAt = time.Now().UTC()
+	r.operations[op.ID] = stored
+	return stored, nil
+}
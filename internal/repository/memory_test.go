@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+const walletID = "550e8400-e29b-41d4-a716-446655440000"
+
+func TestMemory_RollbackDiscardsChanges(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	_, err = tx.InsertWallet(ctx, model.Wallet{WalletID: walletID, Currency: "RUB", Balance: decimal.NewFromInt(10)})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	_, err = repo.GetWallet(ctx, walletID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.Is(tx.Commit(), ErrTxDone))
+}
+
+func TestMemory_UncommittedChangesAreInvisible(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	_, err = tx.InsertWallet(ctx, model.Wallet{WalletID: walletID, Currency: "RUB", Balance: decimal.NewFromInt(10)})
+	require.NoError(t, err)
+
+	_, err = repo.GetWallet(ctx, walletID)
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	locked, err := tx.LockWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.True(t, locked.Balance.Equal(decimal.NewFromInt(10)))
+
+	require.NoError(t, tx.Commit())
+	wallet, err := repo.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(10)))
+}
+
+func TestMemory_TransactionsAreSerialized(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = repo.BeginTx(waitCtx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	require.NoError(t, tx.Commit())
+	next, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, next.Rollback())
+}
+
+func TestMemory_ListTransactionsPagination(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	_, err = tx.InsertWallet(ctx, model.Wallet{WalletID: walletID, Currency: "RUB", Balance: decimal.Zero})
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		_, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:      walletID,
+			Amount:        decimal.NewFromInt(int64(i)),
+			OperationType: model.OperationDeposit,
+			BalanceAfter:  decimal.NewFromInt(int64(i)),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	page, err := repo.ListTransactions(ctx, walletID, 2, 1)
+	require.NoError(t, err)
+	assert.Len(t, page, 2)
+
+	all, err := repo.ListTransactions(ctx, walletID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 5)
+	for i := 1; i < len(all); i++ {
+		assert.False(t, all[i].CreatedAt.After(all[i-1].CreatedAt))
+	}
+}
+
+func TestMemory_ClaimIdempotencyKey(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	_, claimed, err := tx.ClaimIdempotencyKey(ctx, "key", "hash")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	require.NoError(t, tx.CompleteIdempotencyKey(ctx, "key", "transaction"))
+	require.NoError(t, tx.Commit())
+
+	tx, err = repo.BeginTx(ctx)
+	require.NoError(t, err)
+	record, claimed, err := tx.ClaimIdempotencyKey(ctx, "key", "other")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, "hash", record.RequestHash)
+	assert.Equal(t, "transaction", record.TransactionID)
+	require.NoError(t, tx.Rollback())
+}
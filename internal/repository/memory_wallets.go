@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (r *MemoryRepository) ListWallets(ctx context.Context, filter WalletFilter) ([]model.Wallet, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = WalletSortCreatedAt
+	}
+	switch sortBy {
+	case WalletSortCreatedAt, WalletSortUpdatedAt, WalletSortBalance:
+	default:
+		return nil, fmt.Errorf("unknown sort field %q", sortBy)
+	}
+
+	// less сравнивает кошельки в порядке выдачи.
+	less := func(a, b model.Wallet) bool {
+		c := compareWallets(a, b, sortBy)
+		if filter.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wallets := make([]model.Wallet, 0)
+	for _, wallet := range r.wallets {
+		if matchWallet(wallet, filter) && (filter.After == nil || less(*filter.After, wallet)) {
+			wallets = append(wallets, wallet)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return less(wallets[i], wallets[j])
+	})
+	if len(wallets) > filter.Limit {
+		wallets = wallets[:filter.Limit]
+	}
+	return wallets, nil
+}
+
+func matchWallet(wallet model.Wallet, filter WalletFilter) bool {
+	switch {
+	case filter.OwnerID != "" && wallet.OwnerID != filter.OwnerID,
+		filter.Status != "" && wallet.Status != filter.Status,
+		filter.MinBalance != nil && wallet.Balance.LessThan(*filter.MinBalance),
+		filter.MaxBalance != nil && wallet.Balance.GreaterThan(*filter.MaxBalance),
+		!filter.CreatedFrom.IsZero() && wallet.CreatedAt.Before(filter.CreatedFrom),
+		!filter.CreatedTo.IsZero() && !wallet.CreatedAt.Before(filter.CreatedTo),
+		!filter.UpdatedFrom.IsZero() && wallet.UpdatedAt.Before(filter.UpdatedFrom),
+		!filter.UpdatedTo.IsZero() && !wallet.UpdatedAt.Before(filter.UpdatedTo):
+		return false
+	}
+	return true
+}
+
+// compareWallets сравнивает кошельки по полю sortBy, а при равенстве — по ID.
+func compareWallets(a, b model.Wallet, sortBy string) int {
+	var c int
+	switch sortBy {
+	case WalletSortUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case WalletSortBalance:
+		c = a.Balance.Cmp(b.Balance)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.WalletID, b.WalletID)
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// transactionColumns — колонки wallet_transactions t; сторнированная часть
+// транзакции не хранится, а считается по ее сторно.
+const transactionColumns = `t.id, t.wallet_id, t.amount, t.operation_type, t.balance_after, t.counterparty_wallet_id, t.reversal_of, ` +
+	`(SELECT COALESCE(SUM(ABS(r.amount)), 0) FROM wallet_transactions r WHERE r.reversal_of = t.id), t.created_at`
+
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{
+		db: db,
+	}
+}
+
+func (r *PostgresRepository) BeginTx(ctx context.Context) (WalletTx, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &postgresTx{tx: tx}, nil
+}
+
+func (r *PostgresRepository) GetWallet(ctx context.Context, walletID string) (model.Wallet, error) {
+	query := `
+        SELECT wallet_id, balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at
+        FROM wallet_db
+        WHERE wallet_id = $1
+    `
+	wallet, err := scanWallet(r.db.QueryRowContext(ctx, query, walletID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Wallet{}, ErrNotFound
+	}
+	return wallet, err
+}
+
+func (r *PostgresRepository) ListTransactions(ctx context.Context, walletID string, limit, offset int) ([]model.Transaction, error) {
+	query := `
+        SELECT ` + transactionColumns + `
+        FROM wallet_transactions t
+        WHERE t.wallet_id = $1
+        ORDER BY t.created_at DESC, t.id
+        LIMIT $2 OFFSET $3
+    `
+	rows, err := r.db.QueryContext(ctx, query, walletID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]model.Transaction, 0, limit)
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+func (r *PostgresRepository) StreamTransactions(ctx context.Context, walletID string, from, to time.Time, fn func(model.Transaction) error) error {
+	query := `
+        SELECT ` + transactionColumns + `
+        FROM wallet_transactions t
+        WHERE t.wallet_id = $1 AND t.created_at >= $2 AND t.created_at < $3
+        ORDER BY t.created_at, t.id
+    `
+	rows, err := r.db.QueryContext(ctx, query, walletID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type postgresTx struct {
+	tx *sql.Tx
+}
+
+func (t *postgresTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *postgresTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func (t *postgresTx) LockWallet(ctx context.Context, walletID string) (model.Wallet, error) {
+	wallet := model.Wallet{WalletID: walletID}
+	var details walletDetails
+
+	querySelect := `
+            SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at
+            FROM wallet_db
+            WHERE wallet_id = $1
+            FOR UPDATE`
+
+	err := t.tx.QueryRowContext(ctx, querySelect, walletID).Scan(&wallet.Balance, &wallet.HeldBalance, &wallet.Currency, &wallet.Status,
+		&details.ownerID, &details.metadata, &details.labels, &wallet.CreatedAt, &wallet.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Wallet{}, ErrNotFound
+	}
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	return wallet, details.decode(&wallet)
+}
+
+func (t *postgresTx) InsertWallet(ctx context.Context, wallet model.Wallet) (model.Wallet, error) {
+	details, err := encodeWalletDetails(wallet)
+	if err != nil {
+		return model.Wallet{}, err
+	}
+
+	queryInsert := `
+    INSERT INTO wallet_db (wallet_id, balance, currency, status, owner_id, metadata, labels)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING created_at, updated_at`
+	err = t.tx.QueryRowContext(ctx, queryInsert,
+		wallet.WalletID,
+		wallet.Balance,
+		wallet.Currency,
+		wallet.Status,
+		details.ownerID,
+		details.metadata,
+		details.labels,
+	).Scan(&wallet.CreatedAt, &wallet.UpdatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return model.Wallet{}, ErrDuplicate
+	}
+	return wallet, err
+}
+
+func (t *postgresTx) UpdateWallet(ctx context.Context, wallet model.Wallet) error {
+	details, err := encodeWalletDetails(wallet)
+	if err != nil {
+		return err
+	}
+
+	queryUpdate := `
+            UPDATE wallet_db
+            SET status = $1, owner_id = $2, metadata = $3, labels = $4, updated_at = NOW()
+            WHERE wallet_id = $5`
+
+	_, err = t.tx.ExecContext(ctx, queryUpdate, wallet.Status, details.ownerID, details.metadata, details.labels, wallet.WalletID)
+	return err
+}
+
+func (t *postgresTx) UpdateBalance(ctx context.Context, walletID string, balance decimal.Decimal) error {
+	queryUpdate := `
+            UPDATE wallet_db
+            SET balance = $1, updated_at = NOW()
+            WHERE wallet_id = $2`
+
+	_, err := t.tx.ExecContext(ctx, queryUpdate, balance, walletID)
+	return err
+}
+
+func (t *postgresTx) UpdateHeldBalance(ctx context.Context, walletID string, held decimal.Decimal) error {
+	queryUpdate := `
+            UPDATE wallet_db
+            SET held_balance = $1, updated_at = NOW()
+            WHERE wallet_id = $2`
+
+	_, err := t.tx.ExecContext(ctx, queryUpdate, held, walletID)
+	return err
+}
+
+func (t *postgresTx) InsertTransaction(ctx context.Context, transaction model.Transaction) (model.Transaction, error) {
+	transaction.ID = uuid.NewString()
+
+	var counterparty sql.NullString
+	if transaction.CounterpartyWalletID != "" {
+		counterparty = sql.NullString{String: transaction.CounterpartyWalletID, Valid: true}
+	}
+
+	queryInsert := `
+    INSERT INTO wallet_transactions (id, wallet_id, amount, operation_type, balance_after, counterparty_wallet_id, reversal_of)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING created_at`
+	err := t.tx.QueryRowContext(ctx, queryInsert,
+		transaction.ID,
+		transaction.WalletID,
+		transaction.Amount,
+		transaction.OperationType,
+		transaction.BalanceAfter,
+		counterparty,
+		nullString(transaction.ReversalOf),
+	).Scan(&transaction.CreatedAt)
+
+	return transaction, err
+}
+
+func (t *postgresTx) GetTransaction(ctx context.Context, transactionID string) (model.Transaction, error) {
+	query := `
+    SELECT ` + transactionColumns + `
+    FROM wallet_transactions t
+    WHERE t.id = $1`
+	transaction, err := scanTransaction(t.tx.QueryRowContext(ctx, query, transactionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Transaction{}, ErrNotFound
+	}
+	return transaction, err
+}
+
+func (t *postgresTx) ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (IdempotencyRecord, bool, error) {
+	queryInsert := `
+    INSERT INTO idempotency_keys (key, request_hash)
+    VALUES ($1, $2)
+    ON CONFLICT (key) DO NOTHING`
+	res, err := t.tx.ExecContext(ctx, queryInsert, key, requestHash)
+	if err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	if inserted == 1 {
+		return IdempotencyRecord{Key: key, RequestHash: requestHash}, true, nil
+	}
+
+	record := IdempotencyRecord{Key: key}
+	var transactionID sql.NullString
+	querySelect := `
+    SELECT request_hash, transaction_id
+    FROM idempotency_keys
+    WHERE key = $1`
+	if err := t.tx.QueryRowContext(ctx, querySelect, key).Scan(&record.RequestHash, &transactionID); err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	record.TransactionID = transactionID.String
+	return record, false, nil
+}
+
+func (t *postgresTx) CompleteIdempotencyKey(ctx context.Context, key, transactionID string) error {
+	queryUpdate := `
+    UPDATE idempotency_keys
+    SET transaction_id = $1
+    WHERE key = $2`
+	_, err := t.tx.ExecContext(ctx, queryUpdate, transactionID, key)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTransaction(row rowScanner) (model.Transaction, error) {
+	var t model.Transaction
+	var counterparty, reversalOf sql.NullString
+	if err := row.Scan(&t.ID, &t.WalletID, &t.Amount, &t.OperationType, &t.BalanceAfter, &counterparty, &reversalOf, &t.ReversedAmount, &t.CreatedAt); err != nil {
+		return model.Transaction{}, err
+	}
+	t.CounterpartyWalletID = counterparty.String
+	t.ReversalOf = reversalOf.String
+	return t, nil
+}
+
+// walletDetails — владелец, метаданные и метки кошелька в том виде, в каком
+// они хранятся в wallet_db (metadata и labels — JSONB).
+type walletDetails struct {
+	ownerID  sql.NullString
+	metadata []byte
+	labels   []byte
+}
+
+func encodeWalletDetails(wallet model.Wallet) (walletDetails, error) {
+	details := walletDetails{
+		ownerID:  nullString(wallet.OwnerID),
+		metadata: []byte("{}"),
+		labels:   []byte("[]"),
+	}
+	var err error
+	if len(wallet.Metadata) > 0 {
+		if details.metadata, err = json.Marshal(wallet.Metadata); err != nil {
+			return walletDetails{}, err
+		}
+	}
+	if len(wallet.Labels) > 0 {
+		if details.labels, err = json.Marshal(wallet.Labels); err != nil {
+			return walletDetails{}, err
+		}
+	}
+	return details, nil
+}
+
+func (d walletDetails) decode(wallet *model.Wallet) error {
+	wallet.OwnerID = d.ownerID.String
+	wallet.Metadata = nil
+	wallet.Labels = nil
+	if len(d.metadata) > 0 {
+		if err := json.Unmarshal(d.metadata, &wallet.Metadata); err != nil {
+			return err
+		}
+		if len(wallet.Metadata) == 0 {
+			wallet.Metadata = nil
+		}
+	}
+	if len(d.labels) > 0 {
+		if err := json.Unmarshal(d.labels, &wallet.Labels); err != nil {
+			return err
+		}
+		if len(wallet.Labels) == 0 {
+			wallet.Labels = nil
+		}
+	}
+	return nil
+}
+
+func scanWallet(row rowScanner) (model.Wallet, error) {
+	var wallet model.Wallet
+	var details walletDetails
+	if err := row.Scan(&wallet.WalletID, &wallet.Balance, &wallet.HeldBalance, &wallet.Currency, &wallet.Status,
+		&details.ownerID, &details.metadata, &details.labels, &wallet.CreatedAt, &wallet.UpdatedAt); err != nil {
+		return model.Wallet{}, err
+	}
+	return wallet, details.decode(&wallet)
+}
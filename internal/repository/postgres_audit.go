@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// auditLockID — ключ pg_advisory_xact_lock, под которым дописывается цепочка
+// аудита: без него две реплики взяли бы один и тот же PrevHash.
+const auditLockID int64 = 5_713_022_502
+
+func (r *PostgresRepository) AppendAudit(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.AuditEntry{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLockID); err != nil {
+		return model.AuditEntry{}, err
+	}
+
+	querySelect := `
+    SELECT hash
+    FROM audit_log
+    ORDER BY id DESC
+    LIMIT 1`
+	err = tx.QueryRowContext(ctx, querySelect).Scan(&entry.PrevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return model.AuditEntry{}, err
+	}
+
+	// Точность TIMESTAMPTZ — микросекунды: хеш считается по тому же времени,
+	// которое потом будет прочитано из базы.
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+
+	queryInsert := `
+    INSERT INTO audit_log (actor, auth_method, action, wallet_id, balance_before, balance_after,
+        client_ip, request_id, result, created_at, prev_hash, hash)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING id`
+	err = tx.QueryRowContext(ctx, queryInsert,
+		entry.Actor,
+		entry.AuthMethod,
+		entry.Action,
+		entry.WalletID,
+		nullDecimal(entry.BalanceBefore),
+		nullDecimal(entry.BalanceAfter),
+		entry.ClientIP,
+		entry.RequestID,
+		entry.Result,
+		entry.CreatedAt,
+		entry.PrevHash,
+		entry.Hash,
+	).Scan(&entry.ID)
+	if err != nil {
+		return model.AuditEntry{}, err
+	}
+	return entry, tx.Commit()
+}
+
+func (r *PostgresRepository) ListAudit(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.WalletID != "" {
+		where("wallet_id = $%d", filter.WalletID)
+	}
+	if filter.Actor != "" {
+		where("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+	if filter.AfterID > 0 {
+		where("id > $%d", filter.AfterID)
+	}
+
+	query := `
+        SELECT id, actor, auth_method, action, wallet_id, balance_before, balance_after,
+            client_ip, request_id, result, created_at, prev_hash, hash
+        FROM audit_log`
+	if len(conditions) > 0 {
+		query += `
+        WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(`
+        ORDER BY id
+        LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]model.AuditEntry, 0, filter.Limit)
+	for rows.Next() {
+		var e model.AuditEntry
+		var before, after decimal.NullDecimal
+		if err := rows.Scan(&e.ID, &e.Actor, &e.AuthMethod, &e.Action, &e.WalletID, &before, &after,
+			&e.ClientIP, &e.RequestID, &e.Result, &e.CreatedAt, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.BalanceBefore = &before.Decimal
+		}
+		if after.Valid {
+			e.BalanceAfter = &after.Decimal
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func nullDecimal(d *decimal.Decimal) decimal.NullDecimal {
+	if d == nil {
+		return decimal.NullDecimal{}
+	}
+	return decimal.NullDecimal{Decimal: *d, Valid: true}
+}
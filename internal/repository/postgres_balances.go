@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// snapshotLockID — ключ pg_advisory_xact_lock, под которым делаются снимки
+// балансов: реплики не должны одновременно сдвигать balance_snapshot_progress.
+const snapshotLockID int64 = 5_713_022_503
+
+func (r *PostgresRepository) BalanceAt(ctx context.Context, walletID string, at time.Time) (decimal.Decimal, bool, error) {
+	query := `
+    SELECT balance_after
+    FROM wallet_transactions
+    WHERE wallet_id = $1 AND created_at <= $2
+    ORDER BY created_at DESC
+    LIMIT 1`
+	var balance decimal.Decimal
+	err := r.db.QueryRowContext(ctx, query, walletID, at).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Zero, false, nil
+	}
+	if err != nil {
+		return decimal.Zero, false, err
+	}
+	return balance, true, nil
+}
+
+func (r *PostgresRepository) OpeningBalance(ctx context.Context, walletID string) (decimal.Decimal, bool, error) {
+	query := `
+    SELECT balance_after - amount
+    FROM wallet_transactions
+    WHERE wallet_id = $1
+    ORDER BY created_at, id
+    LIMIT 1`
+	var balance decimal.Decimal
+	err := r.db.QueryRowContext(ctx, query, walletID).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Zero, false, nil
+	}
+	if err != nil {
+		return decimal.Zero, false, err
+	}
+	return balance, true, nil
+}
+
+func (r *PostgresRepository) ListBalanceSnapshots(ctx context.Context, walletID string, from, to time.Time) ([]model.BalanceSnapshot, error) {
+	query := `
+    (SELECT day, balance
+     FROM wallet_balance_snapshots
+     WHERE wallet_id = $1 AND day < $2
+     ORDER BY day DESC
+     LIMIT 1)
+    UNION ALL
+    (SELECT day, balance
+     FROM wallet_balance_snapshots
+     WHERE wallet_id = $1 AND day BETWEEN $2 AND $3)
+    ORDER BY day`
+	rows, err := r.db.QueryContext(ctx, query, walletID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []model.BalanceSnapshot
+	for rows.Next() {
+		s := model.BalanceSnapshot{WalletID: walletID}
+		if err := rows.Scan(&s.Day, &s.Balance); err != nil {
+			return nil, err
+		}
+		s.Day = s.Day.UTC()
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+func (r *PostgresRepository) SnapshotHorizon(ctx context.Context) (time.Time, error) {
+	return snapshotHorizon(ctx, r.db)
+}
+
+func (r *PostgresRepository) SnapshotBalances(ctx context.Context, through time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, snapshotLockID); err != nil {
+		return 0, err
+	}
+
+	horizon, err := snapshotHorizon(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	if !horizon.IsZero() && !through.After(horizon) {
+		return 0, nil
+	}
+	var start time.Time
+	if !horizon.IsZero() {
+		start = horizon.AddDate(0, 0, 1)
+	}
+
+	// Снимок дня — balance_after последней транзакции кошелька за этот день.
+	queryInsert := `
+    INSERT INTO wallet_balance_snapshots (wallet_id, day, balance)
+    SELECT DISTINCT ON (wallet_id, (created_at AT TIME ZONE 'UTC')::date)
+        wallet_id, (created_at AT TIME ZONE 'UTC')::date, balance_after
+    FROM wallet_transactions
+    WHERE created_at >= $1 AND created_at < $2
+    ORDER BY wallet_id, (created_at AT TIME ZONE 'UTC')::date, created_at DESC
+    ON CONFLICT (wallet_id, day) DO NOTHING`
+	res, err := tx.ExecContext(ctx, queryInsert, start, through.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	queryProgress := `
+    INSERT INTO balance_snapshot_progress (snapshotted_through)
+    VALUES ($1)
+    ON CONFLICT (id) DO UPDATE SET snapshotted_through = EXCLUDED.snapshotted_through`
+	if _, err := tx.ExecContext(ctx, queryProgress, through); err != nil {
+		return 0, err
+	}
+	return int(inserted), tx.Commit()
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func snapshotHorizon(ctx context.Context, q queryRower) (time.Time, error) {
+	var through time.Time
+	err := q.QueryRowContext(ctx, `SELECT snapshotted_through FROM balance_snapshot_progress`).Scan(&through)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return through.UTC(), err
+}
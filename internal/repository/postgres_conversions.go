@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+const conversionColumns = `id, quote_id, from_wallet_id, to_wallet_id, from_currency, to_currency, rate, debit_amount, credit_amount, remainder, debit_transaction_id, credit_transaction_id, created_at`
+
+func (r *PostgresRepository) InsertQuote(ctx context.Context, quote model.Quote) (model.Quote, error) {
+	query := `
+    INSERT INTO quotes (id, from_currency, to_currency, rate, expires_at)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING created_at`
+	err := r.db.QueryRowContext(ctx, query,
+		quote.ID,
+		quote.FromCurrency,
+		quote.ToCurrency,
+		quote.Rate,
+		quote.ExpiresAt,
+	).Scan(&quote.CreatedAt)
+	return quote, err
+}
+
+func (r *PostgresRepository) GetQuote(ctx context.Context, quoteID string) (model.Quote, error) {
+	query := `
+    SELECT id, from_currency, to_currency, rate, expires_at, created_at
+    FROM quotes
+    WHERE id = $1`
+	var quote model.Quote
+	err := r.db.QueryRowContext(ctx, query, quoteID).Scan(
+		&quote.ID,
+		&quote.FromCurrency,
+		&quote.ToCurrency,
+		&quote.Rate,
+		&quote.ExpiresAt,
+		&quote.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Quote{}, ErrNotFound
+	}
+	return quote, err
+}
+
+func (t *postgresTx) InsertConversion(ctx context.Context, c model.Conversion) (model.Conversion, error) {
+	query := `
+    INSERT INTO conversions (id, quote_id, from_wallet_id, to_wallet_id, from_currency, to_currency, rate,
+        debit_amount, credit_amount, remainder, debit_transaction_id, credit_transaction_id)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING created_at`
+	err := t.tx.QueryRowContext(ctx, query,
+		c.ID,
+		nullString(c.QuoteID),
+		c.FromWalletID,
+		c.ToWalletID,
+		c.FromCurrency,
+		c.ToCurrency,
+		c.Rate,
+		c.DebitAmount,
+		c.CreditAmount,
+		c.Remainder,
+		c.DebitTransactionID,
+		c.CreditTransactionID,
+	).Scan(&c.CreatedAt)
+	return c, err
+}
+
+func (t *postgresTx) GetConversionByTransaction(ctx context.Context, debitTransactionID string) (model.Conversion, error) {
+	query := `SELECT ` + conversionColumns + ` FROM conversions WHERE debit_transaction_id = $1`
+	var c model.Conversion
+	var quoteID sql.NullString
+	err := t.tx.QueryRowContext(ctx, query, debitTransactionID).Scan(
+		&c.ID,
+		&quoteID,
+		&c.FromWalletID,
+		&c.ToWalletID,
+		&c.FromCurrency,
+		&c.ToCurrency,
+		&c.Rate,
+		&c.DebitAmount,
+		&c.CreditAmount,
+		&c.Remainder,
+		&c.DebitTransactionID,
+		&c.CreditTransactionID,
+		&c.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Conversion{}, ErrNotFound
+	}
+	c.QuoteID = quoteID.String
+	return c, err
+}
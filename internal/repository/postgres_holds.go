@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+const holdColumns = `id, wallet_id, amount, captured_amount, status, transaction_id, expires_at, created_at, updated_at`
+
+func (r *PostgresRepository) GetHold(ctx context.Context, holdID string) (model.Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM holds WHERE id = $1`
+	return scanHold(r.db.QueryRowContext(ctx, query, holdID))
+}
+
+func (r *PostgresRepository) ListExpiredHolds(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	query := `
+    SELECT id
+    FROM holds
+    WHERE status = 'active' AND expires_at <= $1
+    ORDER BY expires_at
+    LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (t *postgresTx) InsertHold(ctx context.Context, hold model.Hold) (model.Hold, error) {
+	query := `
+    INSERT INTO holds (id, wallet_id, amount, status, expires_at)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING created_at, updated_at`
+	err := t.tx.QueryRowContext(ctx, query,
+		hold.ID,
+		hold.WalletID,
+		hold.Amount,
+		hold.Status,
+		hold.ExpiresAt,
+	).Scan(&hold.CreatedAt, &hold.UpdatedAt)
+	return hold, err
+}
+
+func (t *postgresTx) LockHold(ctx context.Context, holdID string) (model.Hold, error) {
+	query := `SELECT ` + holdColumns + ` FROM holds WHERE id = $1 FOR UPDATE`
+	return scanHold(t.tx.QueryRowContext(ctx, query, holdID))
+}
+
+func (t *postgresTx) UpdateHold(ctx context.Context, hold model.Hold) (model.Hold, error) {
+	query := `
+    UPDATE holds
+    SET status = $1, captured_amount = $2, transaction_id = $3, updated_at = NOW()
+    WHERE id = $4
+    RETURNING updated_at`
+	err := t.tx.QueryRowContext(ctx, query,
+		hold.Status,
+		hold.CapturedAmount,
+		nullString(hold.TransactionID),
+		hold.ID,
+	).Scan(&hold.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Hold{}, ErrNotFound
+	}
+	return hold, err
+}
+
+func scanHold(row rowScanner) (model.Hold, error) {
+	var hold model.Hold
+	var transactionID sql.NullString
+	err := row.Scan(
+		&hold.ID,
+		&hold.WalletID,
+		&hold.Amount,
+		&hold.CapturedAmount,
+		&hold.Status,
+		&transactionID,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Hold{}, ErrNotFound
+	}
+	if err != nil {
+		return model.Hold{}, err
+	}
+	hold.TransactionID = transactionID.String
+	return hold, nil
+}
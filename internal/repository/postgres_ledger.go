@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (t *postgresTx) InsertPosting(ctx context.Context, posting model.Posting) (model.Posting, error) {
+	if !posting.Balanced() {
+		return model.Posting{}, ErrUnbalancedPosting
+	}
+
+	// Счета заводятся при первой проводке. DO NOTHING не блокирует
+	// существующую строку, поэтому системные счета не становятся узким местом.
+	var accounts []string
+	var accountArgs []any
+	seen := make(map[string]bool, len(posting.Entries))
+	for _, e := range posting.Entries {
+		if seen[e.Account.ID] {
+			continue
+		}
+		seen[e.Account.ID] = true
+		n := len(accountArgs)
+		accounts = append(accounts, fmt.Sprintf("($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4))
+		accountArgs = append(accountArgs, e.Account.ID, e.Account.Kind, e.Account.Currency, nullString(e.Account.WalletID))
+	}
+	queryAccounts := `
+    INSERT INTO ledger_accounts (id, kind, currency, wallet_id)
+    VALUES ` + strings.Join(accounts, ", ") + `
+    ON CONFLICT (id) DO NOTHING`
+	if _, err := t.tx.ExecContext(ctx, queryAccounts, accountArgs...); err != nil {
+		return model.Posting{}, err
+	}
+
+	queryPosting := `
+    INSERT INTO ledger_postings (id, operation_type)
+    VALUES ($1, $2)
+    RETURNING created_at`
+	if err := t.tx.QueryRowContext(ctx, queryPosting, posting.ID, posting.OperationType).Scan(&posting.CreatedAt); err != nil {
+		return model.Posting{}, err
+	}
+
+	var entries []string
+	entryArgs := []any{posting.ID}
+	for _, e := range posting.Entries {
+		n := len(entryArgs)
+		entries = append(entries, fmt.Sprintf("($1, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4))
+		entryArgs = append(entryArgs, e.Account.ID, e.Account.Currency, e.Amount, nullString(e.TransactionID))
+	}
+	queryEntries := `
+    INSERT INTO ledger_entries (posting_id, account_id, currency, amount, transaction_id)
+    VALUES ` + strings.Join(entries, ", ")
+	if _, err := t.tx.ExecContext(ctx, queryEntries, entryArgs...); err != nil {
+		return model.Posting{}, err
+	}
+	return posting, nil
+}
+
+func (r *PostgresRepository) TrialBalance(ctx context.Context, mismatchLimit int) ([]model.LedgerClassBalance, []model.WalletMismatch, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	queryClasses := `
+    SELECT a.kind, e.currency, SUM(e.amount)
+    FROM ledger_entries e
+    JOIN ledger_accounts a ON a.id = e.account_id
+    GROUP BY a.kind, e.currency
+    ORDER BY e.currency, a.kind`
+	rows, err := tx.QueryContext(ctx, queryClasses)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var classes []model.LedgerClassBalance
+	for rows.Next() {
+		var c model.LedgerClassBalance
+		if err := rows.Scan(&c.Kind, &c.Currency, &c.Balance); err != nil {
+			return nil, nil, err
+		}
+		classes = append(classes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	queryMismatches := `
+    SELECT w.wallet_id, w.balance, COALESCE(l.total, 0)
+    FROM wallet_db w
+    LEFT JOIN (
+        SELECT account_id, SUM(amount) AS total
+        FROM ledger_entries
+        GROUP BY account_id
+    ) l ON l.account_id = w.wallet_id::text
+    WHERE w.balance <> COALESCE(l.total, 0)
+    ORDER BY w.wallet_id
+    LIMIT $1`
+	mismatchRows, err := tx.QueryContext(ctx, queryMismatches, mismatchLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer mismatchRows.Close()
+
+	var mismatches []model.WalletMismatch
+	for mismatchRows.Next() {
+		var m model.WalletMismatch
+		if err := mismatchRows.Scan(&m.WalletID, &m.WalletBalance, &m.LedgerBalance); err != nil {
+			return nil, nil, err
+		}
+		mismatches = append(mismatches, m)
+	}
+	return classes, mismatches, mismatchRows.Err()
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+const uniqueViolation = "23505"
+
+const operationColumns = `id, wallet_id, operation_type, amount, currency, to_wallet_id, idempotency_key, status, error_code, transaction_id, created_at, updated_at`
+
+func (r *PostgresRepository) InsertOperation(ctx context.Context, op model.Operation) (model.Operation, error) {
+	query := `
+    INSERT INTO operations (id, wallet_id, operation_type, amount, currency, to_wallet_id, idempotency_key, status)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    RETURNING created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query,
+		op.ID,
+		op.WalletID,
+		op.OperationType,
+		op.Amount,
+		nullString(op.Currency),
+		nullString(op.ToWalletID),
+		nullString(op.IdempotencyKey),
+		op.Status,
+	).Scan(&op.CreatedAt, &op.UpdatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return model.Operation{}, ErrDuplicate
+	}
+	return op, err
+}
+
+func (r *PostgresRepository) GetOperation(ctx context.Context, operationID string) (model.Operation, error) {
+	query := `SELECT ` + operationColumns + ` FROM operations WHERE id = $1`
+	return scanOperation(r.db.QueryRowContext(ctx, query, operationID))
+}
+
+func (r *PostgresRepository) GetOperationByIdempotencyKey(ctx context.Context, key string) (model.Operation, error) {
+	query := `SELECT ` + operationColumns + ` FROM operations WHERE idempotency_key = $1`
+	return scanOperation(r.db.QueryRowContext(ctx, query, key))
+}
+
+func (r *PostgresRepository) UpdateOperation(ctx context.Context, op model.Operation) (model.Operation, error) {
+	query := `
+    UPDATE operations
+    SET status = $1, error_code = $2, transaction_id = $3, updated_at = NOW()
+    WHERE id = $4
+    RETURNING updated_at`
+	err := r.db.QueryRowContext(ctx, query,
+		op.Status,
+		nullString(op.ErrorCode),
+		nullString(op.TransactionID),
+		op.ID,
+	).Scan(&op.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Operation{}, ErrNotFound
+	}
+	return op, err
+}
+
+func (r *PostgresRepository) ListPendingOperations(ctx context.Context, createdBefore time.Time, afterID string, limit int) ([]model.Operation, error) {
+	if afterID == "" {
+		afterID = uuid.Nil.String()
+	}
+	query := `
+    SELECT ` + operationColumns + `
+    FROM operations
+    WHERE status = 'pending' AND created_at < $1 AND id > $2
+    ORDER BY id
+    LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, query, createdBefore, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ops []model.Operation
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	return ops, rows.Err()
+}
+
+func scanOperation(row rowScanner) (model.Operation, error) {
+	var op model.Operation
+	var currency, toWalletID, idempotencyKey, errorCode, transactionID sql.NullString
+	err := row.Scan(
+		&op.ID,
+		&op.WalletID,
+		&op.OperationType,
+		&op.Amount,
+		&currency,
+		&toWalletID,
+		&idempotencyKey,
+		&op.Status,
+		&errorCode,
+		&transactionID,
+		&op.CreatedAt,
+		&op.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Operation{}, ErrNotFound
+	}
+	if err != nil {
+		return model.Operation{}, err
+	}
+	op.Currency = currency.String
+	op.ToWalletID = toWalletID.String
+	op.IdempotencyKey = idempotencyKey.String
+	op.ErrorCode = errorCode.String
+	op.TransactionID = transactionID.String
+	return op, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
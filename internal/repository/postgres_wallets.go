@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+func (r *PostgresRepository) ListWallets(ctx context.Context, filter WalletFilter) ([]model.Wallet, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = WalletSortCreatedAt
+	}
+	switch sortBy {
+	case WalletSortCreatedAt, WalletSortUpdatedAt, WalletSortBalance:
+	default:
+		return nil, fmt.Errorf("unknown sort field %q", sortBy)
+	}
+
+	var conditions []string
+	var args []any
+	where := func(condition string, values ...any) {
+		placeholders := make([]any, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			placeholders[i] = len(args)
+		}
+		conditions = append(conditions, fmt.Sprintf(condition, placeholders...))
+	}
+
+	if filter.OwnerID != "" {
+		where("owner_id = $%d", filter.OwnerID)
+	}
+	if filter.Status != "" {
+		where("status = $%d", filter.Status)
+	}
+	if filter.MinBalance != nil {
+		where("balance >= $%d", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		where("balance <= $%d", *filter.MaxBalance)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		where("created_at >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		where("created_at < $%d", filter.CreatedTo)
+	}
+	if !filter.UpdatedFrom.IsZero() {
+		where("updated_at >= $%d", filter.UpdatedFrom)
+	}
+	if !filter.UpdatedTo.IsZero() {
+		where("updated_at < $%d", filter.UpdatedTo)
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.After != nil {
+		where("("+sortBy+", wallet_id) "+comparison+" ($%d, $%d)", walletSortValue(*filter.After, sortBy), filter.After.WalletID)
+	}
+
+	query := `
+        SELECT wallet_id, balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at
+        FROM wallet_db`
+	if len(conditions) > 0 {
+		query += `
+        WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(`
+        ORDER BY %[1]s %[2]s, wallet_id %[2]s
+        LIMIT $%[3]d`, sortBy, direction, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := make([]model.Wallet, 0, filter.Limit)
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+func walletSortValue(wallet model.Wallet, sortBy string) any {
+	switch sortBy {
+	case WalletSortUpdatedAt:
+		return wallet.UpdatedAt
+	case WalletSortBalance:
+		return wallet.Balance
+	default:
+		return wallet.CreatedAt
+	}
+}
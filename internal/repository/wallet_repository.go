@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("duplicate key")
+	ErrTxDone    = errors.New("transaction has already been committed or rolled back")
+	// ErrUnbalancedPosting — сумма записей проводки в какой-либо валюте не ноль.
+	ErrUnbalancedPosting = errors.New("ledger posting is not balanced")
+)
+
+// Поля сортировки кошельков в ListWallets. При равных значениях порядок
+// определяет wallet_id.
+const (
+	WalletSortCreatedAt = "created_at"
+	WalletSortUpdatedAt = "updated_at"
+	WalletSortBalance   = "balance"
+)
+
+// WalletFilter — условия выборки ListWallets. Пустые поля не ограничивают
+// выборку. Границы баланса включаются, у диапазонов дат нижняя граница
+// включается, верхняя — нет.
+type WalletFilter struct {
+	OwnerID     string
+	Status      string
+	MinBalance  *decimal.Decimal
+	MaxBalance  *decimal.Decimal
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+
+	// SortBy — одно из WalletSort*, по умолчанию WalletSortCreatedAt.
+	SortBy string
+	Desc   bool
+	// After — последний кошелек предыдущей страницы: выборка продолжается
+	// строго после него в порядке сортировки.
+	After *model.Wallet
+	Limit int
+}
+
+type IdempotencyRecord struct {
+	Key           string
+	RequestHash   string
+	TransactionID string
+}
+
+// WalletRepository — хранилище кошельков. Все изменения баланса выполняются
+// внутри WalletTx, которую открывает BeginTx.
+type WalletRepository interface {
+	BeginTx(ctx context.Context) (WalletTx, error)
+	GetWallet(ctx context.Context, walletID string) (model.Wallet, error)
+	ListWallets(ctx context.Context, filter WalletFilter) ([]model.Wallet, error)
+	ListTransactions(ctx context.Context, walletID string, limit, offset int) ([]model.Transaction, error)
+	// StreamTransactions передает fn транзакции кошелька с created_at из
+	// [from, to) по возрастанию времени, не загружая их все в память. Ошибка
+	// fn прерывает выборку и возвращается как есть.
+	StreamTransactions(ctx context.Context, walletID string, from, to time.Time, fn func(model.Transaction) error) error
+
+	// InsertOperation сохраняет асинхронную операцию. Если операция с тем же
+	// ключом идемпотентности уже есть, возвращает ErrDuplicate.
+	InsertOperation(ctx context.Context, op model.Operation) (model.Operation, error)
+	GetOperation(ctx context.Context, operationID string) (model.Operation, error)
+	GetOperationByIdempotencyKey(ctx context.Context, key string) (model.Operation, error)
+	// UpdateOperation сохраняет статус, код ошибки и ID транзакции операции.
+	UpdateOperation(ctx context.Context, op model.Operation) (model.Operation, error)
+	// ListPendingOperations возвращает до limit операций в статусе pending,
+	// созданных раньше createdBefore, с ID больше afterID в порядке ID.
+	ListPendingOperations(ctx context.Context, createdBefore time.Time, afterID string, limit int) ([]model.Operation, error)
+
+	GetHold(ctx context.Context, holdID string) (model.Hold, error)
+	// ListExpiredHolds возвращает ID активных холдов, истекших к моменту before.
+	ListExpiredHolds(ctx context.Context, before time.Time, limit int) ([]string, error)
+
+	InsertQuote(ctx context.Context, quote model.Quote) (model.Quote, error)
+	GetQuote(ctx context.Context, quoteID string) (model.Quote, error)
+
+	// BalanceAt возвращает баланс после последней транзакции кошелька не
+	// позже момента at и found=false, если транзакций до at не было.
+	BalanceAt(ctx context.Context, walletID string, at time.Time) (balance decimal.Decimal, found bool, err error)
+	// OpeningBalance возвращает баланс кошелька до его первой транзакции и
+	// found=false, если транзакций нет.
+	OpeningBalance(ctx context.Context, walletID string) (balance decimal.Decimal, found bool, err error)
+	// ListBalanceSnapshots возвращает по возрастанию дня снимки кошелька за
+	// дни [from, to] и последний снимок до from, если он есть.
+	ListBalanceSnapshots(ctx context.Context, walletID string, from, to time.Time) ([]model.BalanceSnapshot, error)
+	// SnapshotHorizon возвращает последний день, за который сделаны снимки,
+	// или нулевое время, если снимков еще не делали.
+	SnapshotHorizon(ctx context.Context) (time.Time, error)
+	// SnapshotBalances делает снимки за дни после SnapshotHorizon по день
+	// through включительно и возвращает число сохраненных снимков.
+	SnapshotBalances(ctx context.Context, through time.Time) (int, error)
+
+	// TrialBalance возвращает из одного снимка данных балансы счетов книги по
+	// видам и валютам и до mismatchLimit кошельков, чей баланс расходится с
+	// книгой.
+	TrialBalance(ctx context.Context, mismatchLimit int) ([]model.LedgerClassBalance, []model.WalletMismatch, error)
+}
+
+type WalletTx interface {
+	// LockWallet читает кошелек и блокирует его до конца транзакции.
+	// Если кошелька нет, возвращает ErrNotFound.
+	LockWallet(ctx context.Context, walletID string) (model.Wallet, error)
+	// InsertWallet создает кошелек. Если кошелек с таким ID уже есть,
+	// возвращает ErrDuplicate.
+	InsertWallet(ctx context.Context, wallet model.Wallet) (model.Wallet, error)
+	// UpdateWallet сохраняет статус, владельца, метаданные и метки кошелька.
+	// Балансы меняются только через UpdateBalance и UpdateHeldBalance.
+	UpdateWallet(ctx context.Context, wallet model.Wallet) error
+	UpdateBalance(ctx context.Context, walletID string, balance decimal.Decimal) error
+	UpdateHeldBalance(ctx context.Context, walletID string, held decimal.Decimal) error
+	InsertTransaction(ctx context.Context, t model.Transaction) (model.Transaction, error)
+	GetTransaction(ctx context.Context, transactionID string) (model.Transaction, error)
+	// ClaimIdempotencyKey резервирует ключ. Если ключ уже занят, возвращает
+	// сохраненную запись и claimed=false.
+	ClaimIdempotencyKey(ctx context.Context, key, requestHash string) (IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key, transactionID string) error
+	InsertHold(ctx context.Context, hold model.Hold) (model.Hold, error)
+	// LockHold читает холд и блокирует его до конца транзакции.
+	LockHold(ctx context.Context, holdID string) (model.Hold, error)
+	// UpdateHold сохраняет статус, списанную сумму и ID транзакции холда.
+	UpdateHold(ctx context.Context, hold model.Hold) (model.Hold, error)
+	InsertConversion(ctx context.Context, c model.Conversion) (model.Conversion, error)
+	// GetConversionByTransaction ищет обмен по ID транзакции списания.
+	GetConversionByTransaction(ctx context.Context, debitTransactionID string) (model.Conversion, error)
+	// InsertPosting сохраняет проводку и заводит недостающие счета. Проводка с
+	// ненулевой суммой в какой-либо валюте отклоняется с ErrUnbalancedPosting
+	// (в PostgreSQL — при коммите).
+	InsertPosting(ctx context.Context, posting model.Posting) (model.Posting, error)
+	Commit() error
+	Rollback() error
+}
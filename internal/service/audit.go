@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+
+	// auditVerifyBatch — размер страницы, которой VerifyAudit читает журнал.
+	auditVerifyBatch = 1000
+)
+
+// AuditService ведет журнал аудита: запись действий, выборка и проверка
+// целостности цепочки хешей.
+type AuditService interface {
+	Record(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error)
+	ListAudit(ctx context.Context, query AuditQuery) (AuditPage, error)
+	VerifyAudit(ctx context.Context) (AuditVerification, error)
+}
+
+// AuditQuery — фильтры и страница журнала. Cursor — NextCursor предыдущей
+// страницы.
+type AuditQuery struct {
+	WalletID string
+	Actor    string
+	Action   string
+	From     time.Time
+	To       time.Time
+	Cursor   string
+	Limit    int
+}
+
+type AuditPage struct {
+	Entries []model.AuditEntry `json:"entries"`
+	// NextCursor пуст на последней странице.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// AuditVerification — результат проверки цепочки. Если Valid=false,
+// BrokenAtID — первая запись, на которой цепочка не сходится.
+type AuditVerification struct {
+	Checked    int    `json:"checked"`
+	Valid      bool   `json:"valid"`
+	BrokenAtID int64  `json:"brokenAtId,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type AuditServiceImpl struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditServiceImpl {
+	return &AuditServiceImpl{repo: repo}
+}
+
+func (s *AuditServiceImpl) Record(ctx context.Context, entry model.AuditEntry) (model.AuditEntry, error) {
+	recorded, err := s.repo.AppendAudit(ctx, entry)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Errorf("Не удалось записать аудит: action=%s, actor=%s", entry.Action, entry.Actor)
+		return model.AuditEntry{}, err
+	}
+	return recorded, nil
+}
+
+func (s *AuditServiceImpl) ListAudit(ctx context.Context, query AuditQuery) (AuditPage, error) {
+	if query.Limit == 0 {
+		query.Limit = DefaultAuditLimit
+	}
+	if query.Limit < 0 || query.Limit > MaxAuditLimit {
+		return AuditPage{}, ErrInvalidLimit
+	}
+
+	filter := repository.AuditFilter{
+		WalletID: query.WalletID,
+		Actor:    query.Actor,
+		Action:   query.Action,
+		From:     query.From,
+		To:       query.To,
+		Limit:    query.Limit + 1,
+	}
+	if query.Cursor != "" {
+		afterID, err := strconv.ParseInt(query.Cursor, 10, 64)
+		if err != nil || afterID <= 0 {
+			return AuditPage{}, ErrInvalidCursor
+		}
+		filter.AfterID = afterID
+	}
+
+	entries, err := s.repo.ListAudit(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка чтения журнала аудита")
+		return AuditPage{}, err
+	}
+
+	page := AuditPage{Entries: entries}
+	if len(entries) > query.Limit {
+		page.Entries = entries[:query.Limit]
+		page.NextCursor = strconv.FormatInt(page.Entries[query.Limit-1].ID, 10)
+	}
+	return page, nil
+}
+
+// VerifyAudit проходит журнал целиком и пересчитывает хеши. Изменение записи
+// дает несовпадение Hash, удаление или вставка — несовпадение PrevHash со
+// следующей записью.
+func (s *AuditServiceImpl) VerifyAudit(ctx context.Context) (AuditVerification, error) {
+	var result AuditVerification
+	var prevHash string
+	var afterID int64
+	for {
+		entries, err := s.repo.ListAudit(ctx, repository.AuditFilter{AfterID: afterID, Limit: auditVerifyBatch})
+		if err != nil {
+			return AuditVerification{}, err
+		}
+		for _, e := range entries {
+			result.Checked++
+			switch {
+			case e.PrevHash != prevHash:
+				result.BrokenAtID, result.Reason = e.ID, "prevHash does not match the previous entry"
+			case e.Hash != e.ComputeHash():
+				result.BrokenAtID, result.Reason = e.ID, "hash does not match the entry contents"
+			}
+			if result.BrokenAtID != 0 {
+				logger.FromContext(ctx).Errorf("Цепочка аудита нарушена: id=%d, %s", e.ID, result.Reason)
+				return result, nil
+			}
+			prevHash = e.Hash
+			afterID = e.ID
+		}
+		if len(entries) < auditVerifyBatch {
+			result.Valid = true
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const (
+	// MaxBalanceSeriesDays — максимальная длина дневной истории баланса.
+	MaxBalanceSeriesDays = 366
+	// snapshotGracePeriod — сколько ждать после полуночи, прежде чем снимать
+	// прошедший день: транзакции, начатые до полуночи, успевают закоммититься.
+	snapshotGracePeriod = 5 * time.Minute
+)
+
+// BalanceAt возвращает баланс кошелька на момент at. Баланс берется из
+// последней транзакции не позже at, поэтому запрос не зависит от длины истории.
+func (s *WalletServiceImpl) BalanceAt(ctx context.Context, walletID string, at time.Time) (model.HistoricalBalance, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
+	if _, err := uuid.Parse(walletID); err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.HistoricalBalance{}, ErrInvalidWalletID
+	}
+	if at.After(time.Now()) {
+		return model.HistoricalBalance{}, ErrInvalidBalanceTime
+	}
+
+	wallet, err := s.getWallet(ctx, walletID)
+	if err != nil {
+		return model.HistoricalBalance{}, err
+	}
+	if at.Before(wallet.CreatedAt) {
+		return model.HistoricalBalance{}, ErrBalanceBeforeCreation
+	}
+
+	balance, found, err := s.repo.BalanceAt(ctx, walletID, at)
+	if err != nil {
+		return model.HistoricalBalance{}, err
+	}
+	if !found {
+		if balance, err = s.openingBalance(ctx, wallet); err != nil {
+			return model.HistoricalBalance{}, err
+		}
+	}
+	return model.HistoricalBalance{
+		WalletID: walletID,
+		Currency: wallet.Currency,
+		At:       at,
+		Balance:  balance,
+	}, nil
+}
+
+// DailyBalances возвращает баланс кошелька на конец каждого дня (UTC) из
+// [from, to]. Дни до создания кошелька пропускаются, to не позже сегодня.
+// Дни, за которые уже сделаны снимки, читаются одним запросом; остальные —
+// по одному BalanceAt на день.
+func (s *WalletServiceImpl) DailyBalances(ctx context.Context, walletID string, from, to time.Time) (model.BalanceSeries, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
+	if _, err := uuid.Parse(walletID); err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.BalanceSeries{}, ErrInvalidWalletID
+	}
+	from, to = startOfDay(from), startOfDay(to)
+	if today := startOfDay(time.Now()); to.After(today) {
+		to = today
+	}
+	if from.After(to) || to.Sub(from) >= MaxBalanceSeriesDays*24*time.Hour {
+		return model.BalanceSeries{}, ErrInvalidDateRange
+	}
+
+	wallet, err := s.getWallet(ctx, walletID)
+	if err != nil {
+		return model.BalanceSeries{}, err
+	}
+	series := model.BalanceSeries{WalletID: walletID, Currency: wallet.Currency, Days: []model.DailyBalance{}}
+	if created := startOfDay(wallet.CreatedAt); from.Before(created) {
+		from = created
+	}
+
+	horizon, err := s.repo.SnapshotHorizon(ctx)
+	if err != nil {
+		return model.BalanceSeries{}, err
+	}
+
+	// Баланс до первой транзакции нужен только для дней без снимков и без
+	// транзакций, поэтому читается лениво и один раз.
+	var opening *decimal.Decimal
+	openingBalance := func() (decimal.Decimal, error) {
+		if opening == nil {
+			balance, err := s.openingBalance(ctx, wallet)
+			if err != nil {
+				return decimal.Zero, err
+			}
+			opening = &balance
+		}
+		return *opening, nil
+	}
+
+	day := from
+	if !horizon.IsZero() && !from.After(horizon) {
+		last := to
+		if last.After(horizon) {
+			last = horizon
+		}
+		snapshots, err := s.repo.ListBalanceSnapshots(ctx, walletID, from, last)
+		if err != nil {
+			return model.BalanceSeries{}, err
+		}
+		var balance decimal.Decimal
+		next := 0
+		for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+			for next < len(snapshots) && !snapshots[next].Day.After(day) {
+				balance = snapshots[next].Balance
+				next++
+			}
+			if next == 0 {
+				if balance, err = openingBalance(); err != nil {
+					return model.BalanceSeries{}, err
+				}
+			}
+			series.Days = append(series.Days, model.DailyBalance{Date: day.Format(model.DateLayout), Balance: balance})
+		}
+	}
+
+	for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+		balance, found, err := s.repo.BalanceAt(ctx, walletID, day.AddDate(0, 0, 1).Add(-time.Nanosecond))
+		if err != nil {
+			return model.BalanceSeries{}, err
+		}
+		if !found {
+			if balance, err = openingBalance(); err != nil {
+				return model.BalanceSeries{}, err
+			}
+		}
+		series.Days = append(series.Days, model.DailyBalance{Date: day.Format(model.DateLayout), Balance: balance})
+	}
+	return series, nil
+}
+
+// SnapshotBalances делает снимки балансов за все завершившиеся дни, которые
+// еще не сняты, и возвращает число сохраненных снимков.
+func (s *WalletServiceImpl) SnapshotBalances(ctx context.Context, now time.Time) (int, error) {
+	through := startOfDay(now.Add(-snapshotGracePeriod)).AddDate(0, 0, -1)
+	return s.repo.SnapshotBalances(ctx, through)
+}
+
+// openingBalance возвращает баланс кошелька до первой транзакции. У кошельков,
+// созданных до журнала транзакций, истории нет вовсе — их баланс с момента
+// создания равен текущему.
+func (s *WalletServiceImpl) openingBalance(ctx context.Context, wallet model.Wallet) (decimal.Decimal, error) {
+	balance, found, err := s.repo.OpeningBalance(ctx, wallet.WalletID)
+	if err != nil || found {
+		return balance, err
+	}
+	return wallet.Balance, nil
+}
+
+func (s *WalletServiceImpl) getWallet(ctx context.Context, walletID string) (model.Wallet, error) {
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.Wallet{}, ErrWalletNotFound
+	}
+	return wallet, err
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
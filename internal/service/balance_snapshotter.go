@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/logger"
+)
+
+// BalanceSnapshotter периодически сохраняет балансы кошельков на конец
+// прошедших дней. Первый проход выполняется сразу при запуске.
+type BalanceSnapshotter struct {
+	svc      *WalletServiceImpl
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func NewBalanceSnapshotter(svc *WalletServiceImpl, interval time.Duration) *BalanceSnapshotter {
+	return &BalanceSnapshotter{
+		svc:      svc,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (bs *BalanceSnapshotter) Start() {
+	bs.wg.Add(1)
+	go func() {
+		defer bs.wg.Done()
+		ticker := time.NewTicker(bs.interval)
+		defer ticker.Stop()
+		for {
+			bs.snapshot()
+			select {
+			case <-bs.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (bs *BalanceSnapshotter) snapshot() {
+	ctx, cancel := context.WithTimeout(context.Background(), bs.interval)
+	defer cancel()
+
+	saved, err := bs.svc.SnapshotBalances(ctx, time.Now())
+	if err != nil {
+		logger.Log.Errorf("Ошибка снимка балансов: %v", err)
+		return
+	}
+	if saved > 0 {
+		logger.Log.Infof("Сохранены снимки балансов: %d", saved)
+	}
+}
+
+func (bs *BalanceSnapshotter) Stop() {
+	bs.once.Do(func() {
+		close(bs.stop)
+	})
+	bs.wg.Wait()
+}
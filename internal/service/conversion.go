@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/currency"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const (
+	defaultQuoteTTL = 30 * time.Second
+	maxQuoteTTL     = 5 * time.Minute
+)
+
+// ExchangeRateProvider отдает курс: сколько единиц валюты to дают за 1 единицу from.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// CreateQuote фиксирует текущий курс from→to на ttl; нулевой ttl означает
+// значение по умолчанию.
+func (s *WalletServiceImpl) CreateQuote(ctx context.Context, from, to string, ttl time.Duration) (model.Quote, error) {
+	from, to = currency.Normalize(from), currency.Normalize(to)
+	if !currency.Supported(from) || !currency.Supported(to) {
+		return model.Quote{}, ErrInvalidCurrency
+	}
+	if from == to {
+		return model.Quote{}, ErrSameCurrency
+	}
+	if ttl == 0 {
+		ttl = defaultQuoteTTL
+	}
+	if ttl < 0 || ttl > maxQuoteTTL {
+		return model.Quote{}, ErrInvalidQuoteTTL
+	}
+
+	rate, err := s.rate(ctx, from, to)
+	if err != nil {
+		return model.Quote{}, err
+	}
+	return s.repo.InsertQuote(ctx, model.Quote{
+		ID:           uuid.NewString(),
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         rate,
+		ExpiresAt:    time.Now().UTC().Add(ttl),
+	})
+}
+
+// Convert атомарно списывает amount с кошелька fromWalletID и зачисляет
+// эквивалент в валюте кошелька toWalletID. Если передан quoteID, применяется
+// зафиксированный курс, иначе — текущий курс провайдера. Зачисление
+// округляется вниз до точности валюты получателя, отброшенная часть
+// сохраняется в Remainder.
+func (s *WalletServiceImpl) Convert(ctx context.Context, fromWalletID, toWalletID string, amount decimal.Decimal, quoteID string) (model.Conversion, error) {
+	ctx = logger.WithWalletID(ctx, fromWalletID)
+	fromID, err := uuid.Parse(fromWalletID)
+	if err != nil {
+		return model.Conversion{}, ErrInvalidWalletID
+	}
+	toID, err := uuid.Parse(toWalletID)
+	if err != nil {
+		return model.Conversion{}, ErrInvalidWalletID
+	}
+	fromWalletID, toWalletID = fromID.String(), toID.String()
+	if fromWalletID == toWalletID {
+		return model.Conversion{}, ErrSameWallet
+	}
+	if !amount.IsPositive() {
+		return model.Conversion{}, ErrInvalidAmount
+	}
+
+	var quote model.Quote
+	if quoteID != "" {
+		if quote, err = s.getQuote(ctx, quoteID); err != nil {
+			return model.Conversion{}, err
+		}
+	}
+
+	logger.FromContext(ctx).Infof("Попытка обмена: from=%s, to=%s, amount=%s, quote_id=%s", fromWalletID, toWalletID, amount, quoteID)
+
+	var result model.Conversion
+	err = s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		idempotencyKey, hasKey := IdempotencyKey(ctx)
+		if hasKey {
+			hash := requestHash(fromWalletID, model.OperationConversion, canonicalAmount(amount), toWalletID, quoteID)
+			existing, found, err := claimIdempotencyKey(ctx, tx, idempotencyKey, hash)
+			if err != nil {
+				return err
+			}
+			if found {
+				logger.FromContext(ctx).Infof("Повторный запрос с ключом идемпотентности: key=%s, transaction_id=%s", idempotencyKey, existing.ID)
+				result, err = tx.GetConversionByTransaction(ctx, existing.ID)
+				return err
+			}
+		}
+
+		wallets := make(map[string]model.Wallet, 2)
+		for _, walletID := range lockOrder(fromWalletID, toWalletID) {
+			wallet, err := tx.LockWallet(ctx, walletID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrWalletNotFound
+			}
+			if err != nil {
+				return err
+			}
+			wallets[walletID] = wallet
+		}
+		from, to := wallets[fromWalletID], wallets[toWalletID]
+		if err := checkStatus(from, true); err != nil {
+			return err
+		}
+		if err := checkStatus(to, false); err != nil {
+			return err
+		}
+		if from.Currency == to.Currency {
+			return ErrSameCurrency
+		}
+		if err := checkCurrency(from, "", amount); err != nil {
+			return err
+		}
+
+		rate := quote.Rate
+		if quoteID == "" {
+			live, err := s.rate(ctx, from.Currency, to.Currency)
+			if err != nil {
+				return err
+			}
+			rate = live
+		} else if quote.FromCurrency != from.Currency || quote.ToCurrency != to.Currency {
+			return ErrCurrencyMismatch
+		}
+
+		scale, _ := currency.Scale(to.Currency)
+		exact := amount.Mul(rate)
+		credit := exact.RoundDown(scale)
+		if !credit.IsPositive() {
+			return ErrInvalidAmount
+		}
+
+		fromBalance := from.Balance.Sub(amount)
+		if fromBalance.LessThan(from.HeldBalance) {
+			return ErrInsufficientFunds
+		}
+		toBalance := to.Balance.Add(credit)
+		if err := tx.UpdateBalance(ctx, fromWalletID, fromBalance); err != nil {
+			return err
+		}
+		if err := tx.UpdateBalance(ctx, toWalletID, toBalance); err != nil {
+			return err
+		}
+
+		debit, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:             fromWalletID,
+			Amount:               amount.Neg(),
+			OperationType:        model.OperationConversion,
+			BalanceAfter:         fromBalance,
+			CounterpartyWalletID: toWalletID,
+		})
+		if err != nil {
+			return err
+		}
+		creditTx, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:             toWalletID,
+			Amount:               credit,
+			OperationType:        model.OperationConversion,
+			BalanceAfter:         toBalance,
+			CounterpartyWalletID: fromWalletID,
+		})
+		if err != nil {
+			return err
+		}
+		// Проводка обмена сбалансирована в каждой валюте отдельно: списание
+		// уходит в позицию fx валюты отправителя, зачисление приходит из позиции
+		// fx валюты получателя. Остаток округления остается в позиции fx.
+		err = post(ctx, tx, model.OperationConversion,
+			model.LedgerEntry{Account: model.WalletAccount(fromWalletID, from.Currency), Amount: debit.Amount, TransactionID: debit.ID},
+			model.LedgerEntry{Account: model.SystemAccount(model.AccountKindFX, from.Currency), Amount: amount},
+			model.LedgerEntry{Account: model.SystemAccount(model.AccountKindFX, to.Currency), Amount: credit.Neg()},
+			model.LedgerEntry{Account: model.WalletAccount(toWalletID, to.Currency), Amount: creditTx.Amount, TransactionID: creditTx.ID},
+		)
+		if err != nil {
+			return err
+		}
+
+		conversion, err := tx.InsertConversion(ctx, model.Conversion{
+			ID:                  uuid.NewString(),
+			QuoteID:             quoteID,
+			FromWalletID:        fromWalletID,
+			ToWalletID:          toWalletID,
+			FromCurrency:        from.Currency,
+			ToCurrency:          to.Currency,
+			Rate:                rate,
+			DebitAmount:         amount,
+			CreditAmount:        credit,
+			Remainder:           exact.Sub(credit),
+			DebitTransactionID:  debit.ID,
+			CreditTransactionID: creditTx.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if hasKey {
+			if err := tx.CompleteIdempotencyKey(ctx, idempotencyKey, debit.ID); err != nil {
+				return err
+			}
+		}
+		result = conversion
+		return nil
+	})
+	observeOperation(model.OperationConversion, amount, err)
+	if err != nil {
+		return model.Conversion{}, err
+	}
+	return result, nil
+}
+
+func (s *WalletServiceImpl) getQuote(ctx context.Context, quoteID string) (model.Quote, error) {
+	if _, err := uuid.Parse(quoteID); err != nil {
+		return model.Quote{}, ErrQuoteNotFound
+	}
+	quote, err := s.repo.GetQuote(ctx, quoteID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.Quote{}, ErrQuoteNotFound
+	}
+	if err != nil {
+		return model.Quote{}, err
+	}
+	if !quote.ExpiresAt.After(time.Now()) {
+		return model.Quote{}, ErrQuoteExpired
+	}
+	return quote, nil
+}
+
+func (s *WalletServiceImpl) rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	if s.rates == nil {
+		return decimal.Decimal{}, ErrRateNotAvailable
+	}
+	rate, err := s.rates.Rate(ctx, from, to)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Курс %s/%s недоступен: %v", from, to, err)
+		return decimal.Decimal{}, ErrRateNotAvailable
+	}
+	return rate, nil
+}
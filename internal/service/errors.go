@@ -0,0 +1,71 @@
+package service
+
+import "errors"
+
+// Error — доменная ошибка сервиса с машиночитаемым кодом, который
+// обработчики HTTP отдают клиенту как есть.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrInvalidWalletID        = &Error{Code: "INVALID_WALLET_ID", Message: "invalid wallet ID format"}
+	ErrInvalidAmount          = &Error{Code: "INVALID_AMOUNT", Message: "amount must be positive"}
+	ErrInvalidCurrency        = &Error{Code: "INVALID_CURRENCY", Message: "unsupported currency"}
+	ErrCurrencyMismatch       = &Error{Code: "CURRENCY_MISMATCH", Message: "currency does not match the wallet currency"}
+	ErrInvalidAmountScale     = &Error{Code: "INVALID_AMOUNT_SCALE", Message: "amount has more decimal places than the currency allows"}
+	ErrSameCurrency           = &Error{Code: "SAME_CURRENCY", Message: "wallets must have different currencies"}
+	ErrRateNotAvailable       = &Error{Code: "RATE_NOT_AVAILABLE", Message: "exchange rate is not available"}
+	ErrInvalidQuoteTTL        = &Error{Code: "INVALID_QUOTE_TTL", Message: "quote TTL is out of range"}
+	ErrQuoteNotFound          = &Error{Code: "QUOTE_NOT_FOUND", Message: "quote not found"}
+	ErrQuoteExpired           = &Error{Code: "QUOTE_EXPIRED", Message: "quote has expired"}
+	ErrInvalidOperationType   = &Error{Code: "INVALID_OPERATION_TYPE", Message: "unknown operation type"}
+	ErrSameWallet             = &Error{Code: "SAME_WALLET", Message: "cannot transfer to the same wallet"}
+	ErrWalletNotFound         = &Error{Code: "WALLET_NOT_FOUND", Message: "wallet not found"}
+	ErrWalletExists           = &Error{Code: "WALLET_EXISTS", Message: "wallet already exists"}
+	ErrWalletFrozen           = &Error{Code: "WALLET_FROZEN", Message: "wallet is frozen"}
+	ErrWalletClosed           = &Error{Code: "WALLET_CLOSED", Message: "wallet is closed"}
+	ErrWalletNotEmpty         = &Error{Code: "WALLET_NOT_EMPTY", Message: "wallet balance must be zero to close it"}
+	ErrInvalidWalletStatus    = &Error{Code: "INVALID_WALLET_STATUS", Message: "unknown wallet status"}
+	ErrInvalidOwnerID         = &Error{Code: "INVALID_OWNER_ID", Message: "owner ID is empty or too long"}
+	ErrInvalidMetadata        = &Error{Code: "INVALID_METADATA", Message: "metadata is too large"}
+	ErrInvalidLabels          = &Error{Code: "INVALID_LABELS", Message: "labels must be non-empty strings of at most 64 characters, at most 20 labels"}
+	ErrInvalidSort            = &Error{Code: "INVALID_SORT", Message: "unknown sort field"}
+	ErrInvalidLimit           = &Error{Code: "INVALID_LIMIT", Message: "limit is out of range"}
+	ErrInvalidCursor          = &Error{Code: "INVALID_CURSOR", Message: "cursor is malformed or was issued for a different sort order"}
+	ErrInvalidBalanceTime     = &Error{Code: "INVALID_BALANCE_TIME", Message: "balance time is in the future"}
+	ErrBalanceBeforeCreation  = &Error{Code: "BALANCE_BEFORE_CREATION", Message: "wallet did not exist at the requested time"}
+	ErrInvalidDateRange       = &Error{Code: "INVALID_DATE_RANGE", Message: "date range is reversed or longer than 366 days"}
+	ErrOperationNotFound      = &Error{Code: "OPERATION_NOT_FOUND", Message: "operation not found"}
+	ErrHoldNotFound           = &Error{Code: "HOLD_NOT_FOUND", Message: "hold not found"}
+	ErrHoldNotActive          = &Error{Code: "HOLD_NOT_ACTIVE", Message: "hold is already captured or released"}
+	ErrHoldExpired            = &Error{Code: "HOLD_EXPIRED", Message: "hold has expired"}
+	ErrCaptureExceedsHold     = &Error{Code: "CAPTURE_EXCEEDS_HOLD", Message: "capture amount exceeds held amount"}
+	ErrInvalidHoldTTL         = &Error{Code: "INVALID_HOLD_TTL", Message: "hold TTL is out of range"}
+	ErrTransactionNotFound    = &Error{Code: "TRANSACTION_NOT_FOUND", Message: "transaction not found"}
+	ErrNotReversible          = &Error{Code: "NOT_REVERSIBLE", Message: "only deposits and withdrawals can be reversed"}
+	ErrAlreadyReversed        = &Error{Code: "ALREADY_REVERSED", Message: "transaction is already fully reversed"}
+	ErrReversalExceedsAmount  = &Error{Code: "REVERSAL_EXCEEDS_AMOUNT", Message: "reversal amount exceeds the unreversed amount of the transaction"}
+	ErrInsufficientFunds      = &Error{Code: "INSUFFICIENT_FUNDS", Message: "insufficient funds"}
+	ErrIdempotencyKeyConflict = &Error{Code: "IDEMPOTENCY_KEY_CONFLICT", Message: "idempotency key already used with a different request"}
+	ErrQueueFull              = &Error{Code: "QUEUE_FULL", Message: "operation queue is full"}
+	ErrRetriesExhausted       = &Error{Code: "RETRIES_EXHAUSTED", Message: "max retries reached"}
+	ErrStorageUnavailable     = &Error{Code: "STORAGE_UNAVAILABLE", Message: "storage is unavailable"}
+)
+
+const ErrCodeInternal = "INTERNAL_ERROR"
+
+// ErrorCode возвращает код доменной ошибки из цепочки err или
+// ErrCodeInternal, если доменной ошибки в ней нет.
+func ErrorCode(err error) string {
+	var svcErr *Error
+	if errors.As(err, &svcErr) {
+		return svcErr.Code
+	}
+	return ErrCodeInternal
+}
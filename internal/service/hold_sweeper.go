@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/logger"
+)
+
+const holdSweepBatchSize = 100
+
+// HoldSweeper периодически снимает истекшие холды.
+type HoldSweeper struct {
+	svc      *WalletServiceImpl
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func NewHoldSweeper(svc *WalletServiceImpl, interval time.Duration) *HoldSweeper {
+	return &HoldSweeper{
+		svc:      svc,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (hs *HoldSweeper) Start() {
+	hs.wg.Add(1)
+	go func() {
+		defer hs.wg.Done()
+		ticker := time.NewTicker(hs.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hs.stop:
+				return
+			case <-ticker.C:
+				hs.sweep()
+			}
+		}
+	}()
+}
+
+func (hs *HoldSweeper) sweep() {
+	ctx, cancel := context.WithTimeout(context.Background(), hs.interval)
+	defer cancel()
+
+	for {
+		released, err := hs.svc.ReleaseExpiredHolds(ctx, time.Now(), holdSweepBatchSize)
+		if err != nil {
+			logger.Log.Errorf("Ошибка снятия истекших холдов: %v", err)
+			return
+		}
+		if released > 0 {
+			logger.Log.Infof("Сняты истекшие холды: %d", released)
+		}
+		if released == 0 || released < holdSweepBatchSize {
+			return
+		}
+	}
+}
+
+func (hs *HoldSweeper) Stop() {
+	hs.once.Do(func() {
+		close(hs.stop)
+	})
+	hs.wg.Wait()
+}
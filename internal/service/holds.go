@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const maxHoldTTL = 30 * 24 * time.Hour
+
+// PlaceHold резервирует amount на кошельке до момента now+ttl. Зарезервированная
+// сумма остается в балансе, но не может быть списана другими операциями.
+func (s *WalletServiceImpl) PlaceHold(ctx context.Context, walletID string, amount decimal.Decimal, ttl time.Duration) (model.Hold, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
+	if _, err := uuid.Parse(walletID); err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.Hold{}, ErrInvalidWalletID
+	}
+	if !amount.IsPositive() {
+		return model.Hold{}, ErrInvalidAmount
+	}
+	if ttl <= 0 || ttl > maxHoldTTL {
+		return model.Hold{}, ErrInvalidHoldTTL
+	}
+
+	logger.FromContext(ctx).Infof("Попытка резервирования: wallet_id=%s, amount=%s, ttl=%s", walletID, amount, ttl)
+
+	var result model.Hold
+	err := s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		wallet, err := tx.LockWallet(ctx, walletID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrWalletNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkStatus(wallet, true); err != nil {
+			return err
+		}
+		if err := checkCurrency(wallet, "", amount); err != nil {
+			return err
+		}
+		if wallet.Available().LessThan(amount) {
+			return ErrInsufficientFunds
+		}
+		if err := tx.UpdateHeldBalance(ctx, walletID, wallet.HeldBalance.Add(amount)); err != nil {
+			return err
+		}
+
+		hold, err := tx.InsertHold(ctx, model.Hold{
+			ID:        uuid.NewString(),
+			WalletID:  walletID,
+			Amount:    amount,
+			Status:    model.HoldStatusActive,
+			ExpiresAt: time.Now().UTC().Add(ttl),
+		})
+		if err != nil {
+			return err
+		}
+		result = hold
+		return nil
+	})
+	if err != nil {
+		return model.Hold{}, err
+	}
+	return result, nil
+}
+
+func (s *WalletServiceImpl) GetHold(ctx context.Context, holdID string) (model.Hold, error) {
+	if _, err := uuid.Parse(holdID); err != nil {
+		return model.Hold{}, ErrHoldNotFound
+	}
+	hold, err := s.repo.GetHold(ctx, holdID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.Hold{}, ErrHoldNotFound
+	}
+	return hold, err
+}
+
+// CaptureHold списывает amount из холда; нулевой amount означает списание
+// всей суммы. Холд закрывается целиком, остаток возвращается в доступный
+// баланс.
+func (s *WalletServiceImpl) CaptureHold(ctx context.Context, holdID string, amount decimal.Decimal) (model.Transaction, error) {
+	if _, err := uuid.Parse(holdID); err != nil {
+		return model.Transaction{}, ErrHoldNotFound
+	}
+	if amount.IsNegative() {
+		return model.Transaction{}, ErrInvalidAmount
+	}
+
+	var result model.Transaction
+	err := s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		idempotencyKey, hasKey := IdempotencyKey(ctx)
+		if hasKey {
+			hash := requestHash(holdID, model.OperationCapture, canonicalAmount(amount))
+			existing, found, err := claimIdempotencyKey(ctx, tx, idempotencyKey, hash)
+			if err != nil {
+				return err
+			}
+			if found {
+				logger.FromContext(ctx).Infof("Повторный запрос с ключом идемпотентности: key=%s, transaction_id=%s", idempotencyKey, existing.ID)
+				result = existing
+				return nil
+			}
+		}
+
+		hold, err := lockActiveHold(ctx, tx, holdID)
+		if err != nil {
+			return err
+		}
+		captured := amount
+		if captured.IsZero() {
+			captured = hold.Amount
+		}
+		if captured.GreaterThan(hold.Amount) {
+			return ErrCaptureExceedsHold
+		}
+
+		wallet, err := tx.LockWallet(ctx, hold.WalletID)
+		if err != nil {
+			return err
+		}
+		if err := checkStatus(wallet, true); err != nil {
+			return err
+		}
+		if err := checkCurrency(wallet, "", captured); err != nil {
+			return err
+		}
+		// Сначала снимаем холд: ограничение held_balance <= balance проверяется
+		// после каждого UPDATE, и списание холда на весь баланс иначе его нарушит.
+		if err := tx.UpdateHeldBalance(ctx, hold.WalletID, wallet.HeldBalance.Sub(hold.Amount)); err != nil {
+			return err
+		}
+		newBalance := wallet.Balance.Sub(captured)
+		if err := tx.UpdateBalance(ctx, hold.WalletID, newBalance); err != nil {
+			return err
+		}
+
+		t, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:      hold.WalletID,
+			Amount:        captured.Neg(),
+			OperationType: model.OperationCapture,
+			BalanceAfter:  newBalance,
+		})
+		if err != nil {
+			return err
+		}
+		if err := post(ctx, tx, model.OperationCapture, externalEntries(wallet, t)...); err != nil {
+			return err
+		}
+
+		hold.Status = model.HoldStatusCaptured
+		hold.CapturedAmount = captured
+		hold.TransactionID = t.ID
+		if _, err := tx.UpdateHold(ctx, hold); err != nil {
+			return err
+		}
+		if hasKey {
+			if err := tx.CompleteIdempotencyKey(ctx, idempotencyKey, t.ID); err != nil {
+				return err
+			}
+		}
+		result = t
+		return nil
+	})
+	observeOperation(model.OperationCapture, result.Amount.Neg(), err)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	logger.FromContext(ctx).WithField(logger.FieldWalletID, result.WalletID).Infof("Холд списан: hold_id=%s, amount=%s", holdID, result.Amount.Neg())
+	return result, nil
+}
+
+// ReleaseHold снимает резерв, не списывая средства.
+func (s *WalletServiceImpl) ReleaseHold(ctx context.Context, holdID string) (model.Hold, error) {
+	if _, err := uuid.Parse(holdID); err != nil {
+		return model.Hold{}, ErrHoldNotFound
+	}
+
+	var result model.Hold
+	err := s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		hold, err := lockActiveHold(ctx, tx, holdID)
+		if err != nil {
+			return err
+		}
+		result, err = closeHold(ctx, tx, hold, model.HoldStatusReleased)
+		return err
+	})
+	if err != nil {
+		return model.Hold{}, err
+	}
+	logger.FromContext(ctx).WithField(logger.FieldWalletID, result.WalletID).Infof("Холд снят: hold_id=%s, amount=%s", holdID, result.Amount)
+	return result, nil
+}
+
+// ReleaseExpiredHolds снимает до limit холдов, истекших к моменту now, и
+// возвращает их количество.
+func (s *WalletServiceImpl) ReleaseExpiredHolds(ctx context.Context, now time.Time, limit int) (int, error) {
+	ids, err := s.repo.ListExpiredHolds(ctx, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, id := range ids {
+		closed := false
+		err := s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+			closed = false
+			hold, err := tx.LockHold(ctx, id)
+			if err != nil {
+				return err
+			}
+			// Холд могли списать или снять после выборки.
+			if hold.Status != model.HoldStatusActive || hold.ExpiresAt.After(now) {
+				return nil
+			}
+			if _, err := closeHold(ctx, tx, hold, model.HoldStatusExpired); err != nil {
+				return err
+			}
+			closed = true
+			return nil
+		})
+		if err != nil {
+			return released, err
+		}
+		if closed {
+			released++
+		}
+	}
+	return released, nil
+}
+
+func lockActiveHold(ctx context.Context, tx repository.WalletTx, holdID string) (model.Hold, error) {
+	hold, err := tx.LockHold(ctx, holdID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.Hold{}, ErrHoldNotFound
+	}
+	if err != nil {
+		return model.Hold{}, err
+	}
+	if hold.Status != model.HoldStatusActive {
+		return model.Hold{}, ErrHoldNotActive
+	}
+	if !hold.ExpiresAt.After(time.Now()) {
+		return model.Hold{}, ErrHoldExpired
+	}
+	return hold, nil
+}
+
+// closeHold переводит холд в status и возвращает его сумму в доступный баланс.
+func closeHold(ctx context.Context, tx repository.WalletTx, hold model.Hold, status string) (model.Hold, error) {
+	wallet, err := tx.LockWallet(ctx, hold.WalletID)
+	if err != nil {
+		return model.Hold{}, err
+	}
+	if err := tx.UpdateHeldBalance(ctx, hold.WalletID, wallet.HeldBalance.Sub(hold.Amount)); err != nil {
+		return model.Hold{}, err
+	}
+	hold.Status = status
+	return tx.UpdateHold(ctx, hold)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey помечает операцию ключом идемпотентности: повторный вызов
+// с тем же ключом и теми же параметрами вернет исходную транзакцию, не меняя баланс.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key, ok
+}
+
+// canonicalAmount — запись суммы без незначащих нулей, чтобы повтор запроса с
+// "100.00" вместо "100" давал тот же хеш.
+func canonicalAmount(amount decimal.Decimal) string {
+	if amount.Exponent() >= 0 {
+		return amount.StringFixed(0)
+	}
+	return strings.TrimRight(strings.TrimRight(amount.StringFixed(-amount.Exponent()), "0"), ".")
+}
+
+func requestHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// claimIdempotencyKey резервирует ключ в текущей транзакции. Если ключ уже был
+// использован, возвращает ранее записанную транзакцию и found=true.
+func claimIdempotencyKey(ctx context.Context, tx repository.WalletTx, key, hash string) (model.Transaction, bool, error) {
+	record, claimed, err := tx.ClaimIdempotencyKey(ctx, key, hash)
+	if err != nil {
+		return model.Transaction{}, false, err
+	}
+	if claimed {
+		return model.Transaction{}, false, nil
+	}
+	if record.RequestHash != hash || record.TransactionID == "" {
+		return model.Transaction{}, false, ErrIdempotencyKeyConflict
+	}
+
+	t, err := tx.GetTransaction(ctx, record.TransactionID)
+	if err != nil {
+		return model.Transaction{}, false, err
+	}
+	return t, true, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+// maxLedgerMismatches — сколько расходящихся кошельков показывать в TrialBalance.
+const maxLedgerMismatches = 100
+
+// post проводит операцию по счетам книги в той же транзакции, что и изменение
+// балансов кошельков.
+func post(ctx context.Context, tx repository.WalletTx, operationType string, entries ...model.LedgerEntry) error {
+	_, err := tx.InsertPosting(ctx, model.Posting{
+		ID:            uuid.NewString(),
+		OperationType: operationType,
+		Entries:       entries,
+	})
+	return err
+}
+
+// externalEntries — проводка внешнего движения денег по кошельку: пополнение
+// идет со счета cash_in, списание — на счет cash_out.
+func externalEntries(wallet model.Wallet, t model.Transaction) []model.LedgerEntry {
+	counterparty := model.AccountKindCashIn
+	if t.Amount.IsNegative() {
+		counterparty = model.AccountKindCashOut
+	}
+	return []model.LedgerEntry{
+		{Account: model.WalletAccount(wallet.WalletID, wallet.Currency), Amount: t.Amount, TransactionID: t.ID},
+		{Account: model.SystemAccount(counterparty, wallet.Currency), Amount: t.Amount.Neg()},
+	}
+}
+
+// TrialBalance сводит книгу: по каждой валюте дебет должен быть равен
+// кредиту, а баланс каждого кошелька — сумме записей его счета.
+func (s *WalletServiceImpl) TrialBalance(ctx context.Context) (model.TrialBalance, error) {
+	classes, mismatches, err := s.repo.TrialBalance(ctx, maxLedgerMismatches)
+	if err != nil {
+		return model.TrialBalance{}, err
+	}
+
+	result := model.TrialBalance{
+		Balanced:         true,
+		Reconciled:       len(mismatches) == 0,
+		Currencies:       []model.CurrencyTrialBalance{},
+		Accounts:         classes,
+		WalletMismatches: mismatches,
+	}
+	if result.Accounts == nil {
+		result.Accounts = []model.LedgerClassBalance{}
+	}
+	if result.WalletMismatches == nil {
+		result.WalletMismatches = []model.WalletMismatch{}
+	}
+
+	// classes отсортированы по валюте.
+	for _, c := range classes {
+		n := len(result.Currencies)
+		if n == 0 || result.Currencies[n-1].Currency != c.Currency {
+			result.Currencies = append(result.Currencies, model.CurrencyTrialBalance{Currency: c.Currency})
+			n++
+		}
+		total := &result.Currencies[n-1]
+		if c.Balance.IsNegative() {
+			total.Credits = total.Credits.Sub(c.Balance)
+		} else {
+			total.Debits = total.Debits.Add(c.Balance)
+		}
+	}
+	for i := range result.Currencies {
+		total := &result.Currencies[i]
+		total.Balanced = total.Debits.Equal(total.Credits)
+		result.Balanced = result.Balanced && total.Balanced
+	}
+	return result, nil
+}
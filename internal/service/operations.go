@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const operationTimeout = 30 * time.Second
+
+// CreateOperation сохраняет операцию в статусе pending для последующего
+// выполнения пулом воркеров. Если у операции есть ключ идемпотентности и она
+// уже была создана, возвращает существующую операцию и created=false.
+// Операция, отклоненная из-за переполненной очереди, при повторе с тем же
+// ключом снова переводится в pending и возвращается с created=true — ее
+// нужно поставить в очередь.
+func (s *WalletServiceImpl) CreateOperation(ctx context.Context, op model.Operation) (model.Operation, bool, error) {
+	if err := validateOperation(op); err != nil {
+		return model.Operation{}, false, err
+	}
+	currencyCode, err := normalizeCurrency(op.Currency)
+	if err != nil {
+		return model.Operation{}, false, err
+	}
+	op.Currency = currencyCode
+
+	if op.IdempotencyKey != "" {
+		existing, err := s.repo.GetOperationByIdempotencyKey(ctx, op.IdempotencyKey)
+		if err == nil {
+			return s.existingOperation(ctx, existing, op)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return model.Operation{}, false, err
+		}
+	}
+
+	op.ID = uuid.NewString()
+	op.Status = model.OperationStatusPending
+	op.ErrorCode = ""
+	op.TransactionID = ""
+
+	created, err := s.repo.InsertOperation(ctx, op)
+	if errors.Is(err, repository.ErrDuplicate) && op.IdempotencyKey != "" {
+		existing, err := s.repo.GetOperationByIdempotencyKey(ctx, op.IdempotencyKey)
+		if err != nil {
+			return model.Operation{}, false, err
+		}
+		return s.existingOperation(ctx, existing, op)
+	}
+	if err != nil {
+		return model.Operation{}, false, err
+	}
+
+	logger.FromContext(ctx).Infof("Создана асинхронная операция: id=%s, type=%s, wallet_id=%s", created.ID, created.OperationType, created.WalletID)
+	return created, true, nil
+}
+
+func (s *WalletServiceImpl) GetOperation(ctx context.Context, operationID string) (model.Operation, error) {
+	if _, err := uuid.Parse(operationID); err != nil {
+		return model.Operation{}, ErrOperationNotFound
+	}
+	op, err := s.repo.GetOperation(ctx, operationID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.Operation{}, ErrOperationNotFound
+	}
+	return op, err
+}
+
+// FailOperation помечает операцию неуспешной, не выполняя ее.
+func (s *WalletServiceImpl) FailOperation(ctx context.Context, operationID string, cause error) (model.Operation, error) {
+	op, err := s.repo.UpdateOperation(ctx, model.Operation{
+		ID:        operationID,
+		Status:    model.OperationStatusFailed,
+		ErrorCode: ErrorCode(cause),
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.Operation{}, ErrOperationNotFound
+	}
+	return op, err
+}
+
+// executeOperation выполняет операцию и сохраняет ее результат. Операция
+// выполняется под ключом идемпотентности, поэтому повторный запуск той же
+// операции не изменит баланс дважды.
+func (s *WalletServiceImpl) executeOperation(ctx context.Context, op model.Operation) model.Operation {
+	ctx = logger.WithWalletID(ctx, op.WalletID)
+	key := op.IdempotencyKey
+	if key == "" {
+		key = "operation:" + op.ID
+	}
+	execCtx, cancel := context.WithTimeout(WithIdempotencyKey(ctx, key), operationTimeout)
+	defer cancel()
+
+	var t model.Transaction
+	var err error
+	switch op.OperationType {
+	case model.OperationDeposit:
+		t, err = s.Deposit(execCtx, op.WalletID, op.Amount, op.Currency)
+	case model.OperationWithdraw:
+		t, err = s.Withdraw(execCtx, op.WalletID, op.Amount, op.Currency)
+	case model.OperationTransfer:
+		t, err = s.Transfer(execCtx, op.WalletID, op.ToWalletID, op.Amount, op.Currency)
+	default:
+		err = ErrInvalidOperationType
+	}
+
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Асинхронная операция завершилась ошибкой: id=%s, error=%v", op.ID, err)
+		op.Status = model.OperationStatusFailed
+		op.ErrorCode = ErrorCode(err)
+	} else {
+		op.Status = model.OperationStatusSucceeded
+		op.TransactionID = t.ID
+	}
+
+	updated, err := s.repo.UpdateOperation(context.WithoutCancel(ctx), op)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Не удалось сохранить результат операции: id=%s, error=%v", op.ID, err)
+		return op
+	}
+	return updated
+}
+
+func validateOperation(op model.Operation) error {
+	if _, err := uuid.Parse(op.WalletID); err != nil {
+		return ErrInvalidWalletID
+	}
+	if !op.Amount.IsPositive() {
+		return ErrInvalidAmount
+	}
+	switch op.OperationType {
+	case model.OperationDeposit, model.OperationWithdraw:
+		return nil
+	case model.OperationTransfer:
+		if _, err := uuid.Parse(op.ToWalletID); err != nil {
+			return ErrInvalidWalletID
+		}
+		return nil
+	default:
+		return ErrInvalidOperationType
+	}
+}
+
+// existingOperation отвечает на повтор операции с тем же ключом
+// идемпотентности.
+func (s *WalletServiceImpl) existingOperation(ctx context.Context, existing, requested model.Operation) (model.Operation, bool, error) {
+	if err := sameOperation(existing, requested); err != nil {
+		return model.Operation{}, false, err
+	}
+	if existing.Status != model.OperationStatusFailed || existing.ErrorCode != ErrQueueFull.Code {
+		return existing, false, nil
+	}
+
+	// Операция не выполнялась: клиент получил 429 и повторяет запрос.
+	existing.Status = model.OperationStatusPending
+	existing.ErrorCode = ""
+	requeued, err := s.repo.UpdateOperation(ctx, existing)
+	if err != nil {
+		return model.Operation{}, false, err
+	}
+	logger.FromContext(ctx).Infof("Операция, отклоненная из-за очереди, создана повторно: id=%s", requeued.ID)
+	return requeued, true, nil
+}
+
+// sameOperation возвращает ErrIdempotencyKeyConflict, если под ключом уже
+// создана другая операция.
+func sameOperation(existing, requested model.Operation) error {
+	if existing.WalletID != requested.WalletID ||
+		existing.OperationType != requested.OperationType ||
+		!existing.Amount.Equal(requested.Amount) ||
+		existing.Currency != requested.Currency ||
+		existing.ToWalletID != requested.ToWalletID {
+		return ErrIdempotencyKeyConflict
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+// Reverse сторнирует пополнение или списание transactionID на amount; нулевой
+// amount означает всю еще не сторнированную часть. Сторно — отдельная
+// транзакция REVERSAL с обратным знаком, ссылающаяся на исходную. Сумма всех
+// сторно одной транзакции не превышает ее сумму.
+func (s *WalletServiceImpl) Reverse(ctx context.Context, transactionID string, amount decimal.Decimal) (model.Transaction, error) {
+	if _, err := uuid.Parse(transactionID); err != nil {
+		return model.Transaction{}, ErrTransactionNotFound
+	}
+	if amount.IsNegative() {
+		return model.Transaction{}, ErrInvalidAmount
+	}
+
+	var result model.Transaction
+	err := s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		idempotencyKey, hasKey := IdempotencyKey(ctx)
+		if hasKey {
+			hash := requestHash(transactionID, model.OperationReversal, canonicalAmount(amount))
+			existing, found, err := claimIdempotencyKey(ctx, tx, idempotencyKey, hash)
+			if err != nil {
+				return err
+			}
+			if found {
+				logger.FromContext(ctx).Infof("Повторный запрос с ключом идемпотентности: key=%s, transaction_id=%s", idempotencyKey, existing.ID)
+				result = existing
+				return nil
+			}
+		}
+
+		original, err := getTransaction(ctx, tx, transactionID)
+		if err != nil {
+			return err
+		}
+		wallet, err := tx.LockWallet(ctx, original.WalletID)
+		if err != nil {
+			return err
+		}
+		// Перечитываем под блокировкой кошелька: сторно пишутся только под ней,
+		// поэтому сторнированная часть, посчитанная по ним, уже не изменится до
+		// коммита.
+		if original, err = getTransaction(ctx, tx, transactionID); err != nil {
+			return err
+		}
+		if !original.Reversible() {
+			return ErrNotReversible
+		}
+		remaining := original.Unreversed()
+		if !remaining.IsPositive() {
+			return ErrAlreadyReversed
+		}
+		reversed := amount
+		if reversed.IsZero() {
+			reversed = remaining
+		}
+		if reversed.GreaterThan(remaining) {
+			return ErrReversalExceedsAmount
+		}
+
+		change := reversed
+		if original.Amount.IsPositive() {
+			change = reversed.Neg()
+		}
+		if err := checkStatus(wallet, change.IsNegative()); err != nil {
+			return err
+		}
+		if err := checkCurrency(wallet, "", reversed); err != nil {
+			return err
+		}
+		newBalance := wallet.Balance.Add(change)
+		if change.IsNegative() && newBalance.LessThan(wallet.HeldBalance) {
+			return ErrInsufficientFunds
+		}
+		if err := tx.UpdateBalance(ctx, wallet.WalletID, newBalance); err != nil {
+			return err
+		}
+
+		t, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:      wallet.WalletID,
+			Amount:        change,
+			OperationType: model.OperationReversal,
+			BalanceAfter:  newBalance,
+			ReversalOf:    original.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := post(ctx, tx, model.OperationReversal, reversalEntries(wallet, original, t)...); err != nil {
+			return err
+		}
+		if hasKey {
+			if err := tx.CompleteIdempotencyKey(ctx, idempotencyKey, t.ID); err != nil {
+				return err
+			}
+		}
+		result = t
+		return nil
+	})
+	observeOperation(model.OperationReversal, result.Amount.Abs(), err)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	logger.FromContext(ctx).WithField(logger.FieldWalletID, result.WalletID).Infof("Транзакция сторнирована: transaction_id=%s, reversal_id=%s, amount=%s", transactionID, result.ID, result.Amount)
+	return result, nil
+}
+
+func getTransaction(ctx context.Context, tx repository.WalletTx, transactionID string) (model.Transaction, error) {
+	t, err := tx.GetTransaction(ctx, transactionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return model.Transaction{}, ErrTransactionNotFound
+	}
+	return t, err
+}
+
+// reversalEntries — проводка, обратная проводке исходной транзакции: деньги
+// возвращаются на тот же системный счет, с которого пришли или на который ушли.
+func reversalEntries(wallet model.Wallet, original, t model.Transaction) []model.LedgerEntry {
+	counterparty := model.AccountKindCashIn
+	if original.Amount.IsNegative() {
+		counterparty = model.AccountKindCashOut
+	}
+	return []model.LedgerEntry{
+		{Account: model.WalletAccount(wallet.WalletID, wallet.Currency), Amount: t.Amount, TransactionID: t.ID},
+		{Account: model.SystemAccount(counterparty, wallet.Currency), Amount: t.Amount.Neg()},
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// StatementWriter получает выписку по частям: заголовок, операции по
+// возрастанию времени и итог. Реализации пишут выписку потоком.
+type StatementWriter interface {
+	Begin(header model.StatementHeader) error
+	Operation(t model.Transaction) error
+	End(summary model.StatementSummary) error
+}
+
+// Statement формирует выписку по кошельку за дни [from, to] (UTC): баланс на
+// начало периода, каждую операцию и баланс на конец. Операции читаются из
+// хранилища потоком и сразу передаются w. Ошибки проверки возвращаются до
+// вызова w.Begin.
+func (s *WalletServiceImpl) Statement(ctx context.Context, walletID string, from, to time.Time, w StatementWriter) error {
+	ctx = logger.WithWalletID(ctx, walletID)
+	if _, err := uuid.Parse(walletID); err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return ErrInvalidWalletID
+	}
+	from, to = startOfDay(from), startOfDay(to)
+	if today := startOfDay(time.Now()); to.After(today) {
+		to = today
+	}
+	if from.After(to) || to.Sub(from) >= MaxBalanceSeriesDays*24*time.Hour {
+		return ErrInvalidDateRange
+	}
+	end := to.AddDate(0, 0, 1)
+
+	wallet, err := s.getWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	opening, found, err := s.repo.BalanceAt(ctx, walletID, from.Add(-time.Nanosecond))
+	if err != nil {
+		return err
+	}
+	if !found {
+		if opening, err = s.openingBalance(ctx, wallet); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Begin(model.StatementHeader{
+		WalletID:       walletID,
+		Currency:       wallet.Currency,
+		From:           from,
+		To:             end,
+		OpeningBalance: opening,
+	}); err != nil {
+		return err
+	}
+
+	summary := model.StatementSummary{ClosingBalance: opening}
+	err = s.repo.StreamTransactions(ctx, walletID, from, end, func(t model.Transaction) error {
+		summary.ClosingBalance = t.BalanceAfter
+		summary.OperationCount++
+		if t.Amount.IsNegative() {
+			summary.TotalDebits = summary.TotalDebits.Sub(t.Amount)
+		} else {
+			summary.TotalCredits = summary.TotalCredits.Add(t.Amount)
+		}
+		return w.Operation(t)
+	})
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Infof("Выписка сформирована: операций %d", summary.OperationCount)
+	return w.End(summary)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+// Transfer атомарно переводит amount с кошелька fromWalletID на toWalletID и
+// возвращает запись о списании. Строки кошельков блокируются в порядке
+// возрастания ID, чтобы встречные переводы не приводили к взаимоблокировке.
+func (s *WalletServiceImpl) Transfer(ctx context.Context, fromWalletID, toWalletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error) {
+	ctx = logger.WithWalletID(ctx, fromWalletID)
+	fromID, err := uuid.Parse(fromWalletID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", fromWalletID)
+		return model.Transaction{}, ErrInvalidWalletID
+	}
+	toID, err := uuid.Parse(toWalletID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", toWalletID)
+		return model.Transaction{}, ErrInvalidWalletID
+	}
+	fromWalletID, toWalletID = fromID.String(), toID.String()
+
+	if fromWalletID == toWalletID {
+		return model.Transaction{}, ErrSameWallet
+	}
+	if !amount.IsPositive() {
+		return model.Transaction{}, ErrInvalidAmount
+	}
+	currencyCode, err = normalizeCurrency(currencyCode)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+
+	logger.FromContext(ctx).Infof("Попытка перевода: from=%s, to=%s, amount=%s", fromWalletID, toWalletID, amount)
+
+	var result model.Transaction
+	err = s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		idempotencyKey, hasKey := IdempotencyKey(ctx)
+		if hasKey {
+			hash := requestHash(fromWalletID, model.OperationTransfer, canonicalAmount(amount), toWalletID, currencyCode)
+			existing, found, err := claimIdempotencyKey(ctx, tx, idempotencyKey, hash)
+			if err != nil {
+				return err
+			}
+			if found {
+				logger.FromContext(ctx).Infof("Повторный запрос с ключом идемпотентности: key=%s, transaction_id=%s", idempotencyKey, existing.ID)
+				result = existing
+				return nil
+			}
+		}
+
+		wallets := make(map[string]model.Wallet, 2)
+		for _, walletID := range lockOrder(fromWalletID, toWalletID) {
+			wallet, err := tx.LockWallet(ctx, walletID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return ErrWalletNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if err := checkCurrency(wallet, currencyCode, amount); err != nil {
+				return err
+			}
+			wallets[walletID] = wallet
+		}
+		if err := checkStatus(wallets[fromWalletID], true); err != nil {
+			return err
+		}
+		if err := checkStatus(wallets[toWalletID], false); err != nil {
+			return err
+		}
+		if wallets[fromWalletID].Currency != wallets[toWalletID].Currency {
+			return ErrCurrencyMismatch
+		}
+
+		fromBalance := wallets[fromWalletID].Balance.Sub(amount)
+		if fromBalance.LessThan(wallets[fromWalletID].HeldBalance) {
+			return ErrInsufficientFunds
+		}
+		toBalance := wallets[toWalletID].Balance.Add(amount)
+
+		if err := tx.UpdateBalance(ctx, fromWalletID, fromBalance); err != nil {
+			return err
+		}
+		if err := tx.UpdateBalance(ctx, toWalletID, toBalance); err != nil {
+			return err
+		}
+
+		debit, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:             fromWalletID,
+			Amount:               amount.Neg(),
+			OperationType:        model.OperationTransfer,
+			BalanceAfter:         fromBalance,
+			CounterpartyWalletID: toWalletID,
+		})
+		if err != nil {
+			return err
+		}
+		credit, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:             toWalletID,
+			Amount:               amount,
+			OperationType:        model.OperationTransfer,
+			BalanceAfter:         toBalance,
+			CounterpartyWalletID: fromWalletID,
+		})
+		if err != nil {
+			return err
+		}
+		walletCurrency := wallets[fromWalletID].Currency
+		err = post(ctx, tx, model.OperationTransfer,
+			model.LedgerEntry{Account: model.WalletAccount(fromWalletID, walletCurrency), Amount: debit.Amount, TransactionID: debit.ID},
+			model.LedgerEntry{Account: model.WalletAccount(toWalletID, walletCurrency), Amount: credit.Amount, TransactionID: credit.ID},
+		)
+		if err != nil {
+			return err
+		}
+
+		if hasKey {
+			if err := tx.CompleteIdempotencyKey(ctx, idempotencyKey, debit.ID); err != nil {
+				return err
+			}
+		}
+		result = debit
+		return nil
+	})
+	observeOperation(model.OperationTransfer, amount, err)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	return result, nil
+}
+
+func lockOrder(a, b string) []string {
+	if a < b {
+		return []string{a, b}
+	}
+	return []string{b, a}
+}
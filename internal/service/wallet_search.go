@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const (
+	DefaultWalletsLimit = 50
+	MaxWalletsLimit     = 500
+)
+
+// walletSortFields сопоставляет поля сортировки API с колонками хранилища.
+var walletSortFields = map[string]string{
+	"createdAt": repository.WalletSortCreatedAt,
+	"updatedAt": repository.WalletSortUpdatedAt,
+	"balance":   repository.WalletSortBalance,
+}
+
+// WalletQuery — фильтры, сортировка и страница списка кошельков. SortBy —
+// createdAt (по умолчанию), updatedAt или balance; Cursor — NextCursor
+// предыдущей страницы, он действует только с теми же SortBy и Desc.
+type WalletQuery struct {
+	OwnerID     string
+	Status      string
+	MinBalance  *decimal.Decimal
+	MaxBalance  *decimal.Decimal
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+	SortBy      string
+	Desc        bool
+	Cursor      string
+	Limit       int
+}
+
+type WalletPage struct {
+	Wallets []model.Wallet `json:"wallets"`
+	// NextCursor пуст на последней странице.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// walletCursor — содержимое непрозрачного курсора: поле сортировки и
+// значения ключа последнего кошелька страницы.
+type walletCursor struct {
+	SortBy   string `json:"s"`
+	Desc     bool   `json:"d,omitempty"`
+	Value    string `json:"v"`
+	WalletID string `json:"id"`
+}
+
+// ListWallets возвращает страницу кошельков для бэк-офиса. Пагинация
+// курсорная: следующая страница начинается строго после последнего
+// кошелька предыдущей, поэтому вставки не сдвигают выдачу.
+func (s *WalletServiceImpl) ListWallets(ctx context.Context, query WalletQuery) (WalletPage, error) {
+	if query.SortBy == "" {
+		query.SortBy = "createdAt"
+	}
+	sortBy, ok := walletSortFields[query.SortBy]
+	if !ok {
+		return WalletPage{}, ErrInvalidSort
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultWalletsLimit
+	}
+	if query.Limit < 0 || query.Limit > MaxWalletsLimit {
+		return WalletPage{}, ErrInvalidLimit
+	}
+	switch query.Status {
+	case "", model.WalletStatusActive, model.WalletStatusFrozen, model.WalletStatusClosed:
+	default:
+		return WalletPage{}, ErrInvalidWalletStatus
+	}
+
+	filter := repository.WalletFilter{
+		OwnerID:     query.OwnerID,
+		Status:      query.Status,
+		MinBalance:  query.MinBalance,
+		MaxBalance:  query.MaxBalance,
+		CreatedFrom: query.CreatedFrom,
+		CreatedTo:   query.CreatedTo,
+		UpdatedFrom: query.UpdatedFrom,
+		UpdatedTo:   query.UpdatedTo,
+		SortBy:      sortBy,
+		Desc:        query.Desc,
+		// Лишняя запись показывает, есть ли следующая страница.
+		Limit: query.Limit + 1,
+	}
+	if query.Cursor != "" {
+		after, err := decodeWalletCursor(query.Cursor, query.SortBy, query.Desc)
+		if err != nil {
+			return WalletPage{}, err
+		}
+		filter.After = &after
+	}
+
+	wallets, err := s.repo.ListWallets(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Error("Ошибка получения списка кошельков")
+		return WalletPage{}, err
+	}
+
+	page := WalletPage{Wallets: wallets}
+	if len(wallets) > query.Limit {
+		page.Wallets = wallets[:query.Limit]
+		page.NextCursor = encodeWalletCursor(page.Wallets[query.Limit-1], query.SortBy, query.Desc)
+	}
+	return page, nil
+}
+
+func encodeWalletCursor(wallet model.Wallet, sortBy string, desc bool) string {
+	cursor := walletCursor{SortBy: sortBy, Desc: desc, WalletID: wallet.WalletID}
+	switch sortBy {
+	case "updatedAt":
+		cursor.Value = wallet.UpdatedAt.Format(time.RFC3339Nano)
+	case "balance":
+		cursor.Value = wallet.Balance.String()
+	default:
+		cursor.Value = wallet.CreatedAt.Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeWalletCursor восстанавливает ключ последнего кошелька страницы.
+// Курсор, выданный для другой сортировки, отклоняется.
+func decodeWalletCursor(encoded, sortBy string, desc bool) (model.Wallet, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return model.Wallet{}, ErrInvalidCursor
+	}
+	var cursor walletCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return model.Wallet{}, ErrInvalidCursor
+	}
+	if cursor.SortBy != sortBy || cursor.Desc != desc || cursor.WalletID == "" {
+		return model.Wallet{}, ErrInvalidCursor
+	}
+
+	wallet := model.Wallet{WalletID: cursor.WalletID}
+	switch sortBy {
+	case "updatedAt":
+		wallet.UpdatedAt, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	case "balance":
+		wallet.Balance, err = decimal.NewFromString(cursor.Value)
+	default:
+		wallet.CreatedAt, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	}
+	if err != nil {
+		return model.Wallet{}, ErrInvalidCursor
+	}
+	return wallet, nil
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"net"
@@ -11,8 +10,16 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/currency"
 	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/metrics"
 	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+	"github.com/sunriseex/test_wallet/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -22,40 +29,57 @@ const (
 )
 
 type WalletService interface {
+	CreateWallet(ctx context.Context, params CreateWalletParams) (model.Wallet, error)
+	UpdateWallet(ctx context.Context, walletID string, params UpdateWalletParams) (model.Wallet, error)
+	ListWalletsByOwner(ctx context.Context, ownerID string) ([]model.Wallet, error)
+	ListWallets(ctx context.Context, query WalletQuery) (WalletPage, error)
 	GetBalance(ctx context.Context, walletID string) (model.Wallet, error)
-	Deposit(ctx context.Context, walletID string, amount decimal.Decimal) error
-	Withdraw(ctx context.Context, walletID string, amount decimal.Decimal) error
+	// Deposit, Withdraw и Transfer принимают код валюты операции. Пустой код
+	// означает валюту кошелька.
+	Deposit(ctx context.Context, walletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error)
+	Withdraw(ctx context.Context, walletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error)
+	Transfer(ctx context.Context, fromWalletID, toWalletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error)
+	GetTransactions(ctx context.Context, walletID string, limit, offset int) ([]model.Transaction, error)
+	Reverse(ctx context.Context, transactionID string, amount decimal.Decimal) (model.Transaction, error)
+	BalanceAt(ctx context.Context, walletID string, at time.Time) (model.HistoricalBalance, error)
+	DailyBalances(ctx context.Context, walletID string, from, to time.Time) (model.BalanceSeries, error)
+	Statement(ctx context.Context, walletID string, from, to time.Time, w StatementWriter) error
+	TrialBalance(ctx context.Context) (model.TrialBalance, error)
+	CreateOperation(ctx context.Context, op model.Operation) (model.Operation, bool, error)
+	GetOperation(ctx context.Context, operationID string) (model.Operation, error)
+	FailOperation(ctx context.Context, operationID string, cause error) (model.Operation, error)
+	PlaceHold(ctx context.Context, walletID string, amount decimal.Decimal, ttl time.Duration) (model.Hold, error)
+	GetHold(ctx context.Context, holdID string) (model.Hold, error)
+	CaptureHold(ctx context.Context, holdID string, amount decimal.Decimal) (model.Transaction, error)
+	ReleaseHold(ctx context.Context, holdID string) (model.Hold, error)
+	CreateQuote(ctx context.Context, from, to string, ttl time.Duration) (model.Quote, error)
+	Convert(ctx context.Context, fromWalletID, toWalletID string, amount decimal.Decimal, quoteID string) (model.Conversion, error)
 }
 
 type WalletServiceImpl struct {
-	db *sql.DB
+	repo  repository.WalletRepository
+	rates ExchangeRateProvider
 }
 
-func NewWalletService(db *sql.DB) *WalletServiceImpl {
+func NewWalletService(repo repository.WalletRepository, rates ExchangeRateProvider) *WalletServiceImpl {
 	return &WalletServiceImpl{
-		db: db,
+		repo:  repo,
+		rates: rates,
 	}
 }
 
 func (s *WalletServiceImpl) GetBalance(ctx context.Context, walletID string) (model.Wallet, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
 	if _, err := uuid.Parse(walletID); err != nil {
-		logger.Log.Errorf("Неверный формат UUID: %s", walletID)
-		return model.Wallet{}, errors.New("invalid wallet ID format")
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.Wallet{}, ErrInvalidWalletID
 
 	}
-	var wallet model.Wallet
-
-	logger.Log.Info("Запрос к базе данных для получения баланса")
-	query := `
-        SELECT wallet_id, balance, created_at, updated_at
-        FROM wallet_db
-        WHERE wallet_id = $1
-    `
-	row := s.db.QueryRowContext(ctx, query, walletID)
-	err := row.Scan(&wallet.WalletID, &wallet.Balance, &wallet.CreatedAt, &wallet.UpdatedAt)
+	logger.FromContext(ctx).Info("Запрос к базе данных для получения баланса")
+	wallet, err := s.repo.GetWallet(ctx, walletID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return model.Wallet{}, sql.ErrNoRows
+		if errors.Is(err, repository.ErrNotFound) {
+			return model.Wallet{}, ErrWalletNotFound
 		}
 		return model.Wallet{}, err
 	}
@@ -63,152 +87,275 @@ func (s *WalletServiceImpl) GetBalance(ctx context.Context, walletID string) (mo
 	return wallet, nil
 }
 
-func (s *WalletServiceImpl) Deposit(ctx context.Context, walletID string, amount decimal.Decimal) error {
-
+func (s *WalletServiceImpl) Deposit(ctx context.Context, walletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
 	if _, err := uuid.Parse(walletID); err != nil {
-		logger.Log.Errorf("Неверный формат UUID: %s", walletID)
-		return errors.New("invalid wallet ID format")
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.Transaction{}, ErrInvalidWalletID
 	}
-
-	if amount.IsZero() {
-		return nil
+	if !amount.IsPositive() {
+		return model.Transaction{}, ErrInvalidAmount
+	}
+	currencyCode, err := normalizeCurrency(currencyCode)
+	if err != nil {
+		return model.Transaction{}, err
 	}
 
-	logger.Log.Infof("Попытка депозита: wallet_id=%s, amount=%s", walletID, amount)
-	return s.updateBalance(ctx, walletID, amount)
+	logger.FromContext(ctx).Infof("Попытка депозита: wallet_id=%s, amount=%s", walletID, amount)
+	t, err := s.updateBalance(ctx, walletID, currencyCode, model.OperationDeposit, amount)
+	observeOperation(model.OperationDeposit, amount, err)
+	return t, err
 }
 
-func (s *WalletServiceImpl) Withdraw(ctx context.Context, walletID string, amount decimal.Decimal) error {
+func (s *WalletServiceImpl) Withdraw(ctx context.Context, walletID string, amount decimal.Decimal, currencyCode string) (model.Transaction, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
 	if _, err := uuid.Parse(walletID); err != nil {
-		logger.Log.Errorf("Неверный формат UUID: %s", walletID)
-		return errors.New("invalid wallet ID format")
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.Transaction{}, ErrInvalidWalletID
+	}
+	if !amount.IsPositive() {
+		return model.Transaction{}, ErrInvalidAmount
 	}
-	logger.Log.Infof("Попытка снятия: wallet_id=%s, amount=%s", walletID, amount)
-	return s.updateBalance(ctx, walletID, amount.Neg())
+	currencyCode, err := normalizeCurrency(currencyCode)
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	logger.FromContext(ctx).Infof("Попытка снятия: wallet_id=%s, amount=%s", walletID, amount)
+	t, err := s.updateBalance(ctx, walletID, currencyCode, model.OperationWithdraw, amount.Neg())
+	observeOperation(model.OperationWithdraw, amount, err)
+	return t, err
 }
 
-func (s *WalletServiceImpl) updateBalance(ctx context.Context, walletID string, change decimal.Decimal) error {
-	return s.executeWithRetry(ctx, func(tx *sql.Tx) error {
-		var currentBalance decimal.Decimal
-		var createdAt, updatedAt time.Time
+func (s *WalletServiceImpl) GetTransactions(ctx context.Context, walletID string, limit, offset int) ([]model.Transaction, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
+	if _, err := uuid.Parse(walletID); err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return nil, ErrInvalidWalletID
+	}
 
-		querySelect := `
-            SELECT balance, created_at, updated_at
-            FROM wallet_db
-            WHERE wallet_id = $1
-            FOR UPDATE`
+	return s.repo.ListTransactions(ctx, walletID, limit, offset)
+}
 
-		row := tx.QueryRowContext(ctx, querySelect, walletID)
-		err := row.Scan(&currentBalance, &createdAt, &updatedAt)
+func (s *WalletServiceImpl) updateBalance(ctx context.Context, walletID, currencyCode, operationType string, change decimal.Decimal) (model.Transaction, error) {
+	var result model.Transaction
 
-		if errors.Is(err, sql.ErrNoRows) {
-			newBalance := change
-			if newBalance.IsNegative() {
-				return errors.New("insufficient funds: wallet not found and negative deposit is not possible")
+	err := s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		idempotencyKey, hasKey := IdempotencyKey(ctx)
+		if hasKey {
+			existing, found, err := claimIdempotencyKey(ctx, tx, idempotencyKey, requestHash(walletID, operationType, canonicalAmount(change), currencyCode))
+			if err != nil {
+				return err
+			}
+			if found {
+				logger.FromContext(ctx).Infof("Повторный запрос с ключом идемпотентности: key=%s, transaction_id=%s", idempotencyKey, existing.ID)
+				result = existing
+				return nil
 			}
-			return createWallet(tx, walletID, newBalance)
+		}
+
+		wallet, err := tx.LockWallet(ctx, walletID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrWalletNotFound
 		}
 		if err != nil {
 			return err
 		}
-
-		newBalance := currentBalance.Add(change)
-		if newBalance.IsNegative() {
-			return errors.New("insufficient funds")
+		if err := checkStatus(wallet, change.IsNegative()); err != nil {
+			return err
+		}
+		if err := checkCurrency(wallet, currencyCode, change); err != nil {
+			return err
+		}
+		newBalance := wallet.Balance.Add(change)
+		if newBalance.LessThan(wallet.HeldBalance) {
+			return ErrInsufficientFunds
+		}
+		if err := tx.UpdateBalance(ctx, walletID, newBalance); err != nil {
+			return err
 		}
 
-		queryUpdate := `
-            UPDATE wallet_db
-            SET balance = $1, updated_at = NOW()
-            WHERE wallet_id = $2`
-
-		_, err = tx.ExecContext(ctx, queryUpdate, newBalance, walletID)
-		return err
+		t, err := tx.InsertTransaction(ctx, model.Transaction{
+			WalletID:      walletID,
+			Amount:        change,
+			OperationType: operationType,
+			BalanceAfter:  newBalance,
+		})
+		if err != nil {
+			return err
+		}
+		if err := post(ctx, tx, operationType, externalEntries(wallet, t)...); err != nil {
+			return err
+		}
+		if hasKey {
+			if err := tx.CompleteIdempotencyKey(ctx, idempotencyKey, t.ID); err != nil {
+				return err
+			}
+		}
+		result = t
+		return nil
 	})
+	if err != nil {
+		return model.Transaction{}, err
+	}
+	return result, nil
 }
 
-func (s *WalletServiceImpl) executeWithRetry(ctx context.Context, fn func(*sql.Tx) error) error {
+// executeWithRetry выполняет fn в транзакции и повторяет попытку при
+// ретраябельных ошибках. Каждая попытка — отдельный спан с SQLSTATE ошибки;
+// SQL-запросы fn становятся его дочерними спанами, если fn использует
+// переданный ей ctx.
+func (s *WalletServiceImpl) executeWithRetry(ctx context.Context, fn func(context.Context, repository.WalletTx) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, "wallet.executeWithRetry")
+	defer span.End()
+
 	var lastErr error
 
 	for i := 0; i < maxRetries; i++ {
 
 		if ctx.Err() != nil {
-			return fmt.Errorf("operation canceled: %w", ctx.Err())
+			err := fmt.Errorf("operation canceled: %w", ctx.Err())
+			endSpan(span, err)
+			return err
 		}
 
-		tx, err := s.db.BeginTx(ctx, &sql.TxOptions{
-			Isolation: sql.LevelReadCommitted,
-		})
-		if err != nil {
-
-			if isRetriableError(err) {
-				lastErr = err
-				logRetry(i, err)
-				time.Sleep(calculateDelay(i))
-				continue
-			}
-			return fmt.Errorf("non-retriable begin error: %w", err)
+		retriable, err := s.attempt(ctx, i, fn)
+		if err == nil {
+			span.SetAttributes(attribute.Int("wallet.tx.attempts", i+1))
+			return nil
+		}
+		if !retriable {
+			span.SetAttributes(attribute.Int("wallet.tx.attempts", i+1))
+			endSpan(span, err)
+			return err
 		}
 
-		if err := fn(tx); err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				logger.Log.Errorf("Rollback failed: %v", rbErr)
-			}
+		lastErr = err
+		logRetry(ctx, i, err)
+		time.Sleep(calculateDelay(i))
+	}
 
-			if !isRetriableError(err) {
-				return fmt.Errorf("non-retriable error: %w", err)
-			}
+	metrics.TxGiveUps.WithLabelValues(sqlState(lastErr)).Inc()
+	err := fmt.Errorf("%w (%d attempts). Last error: %w", ErrRetriesExhausted, maxRetries, lastErr)
+	span.SetAttributes(attribute.Int("wallet.tx.attempts", maxRetries))
+	endSpan(span, err)
+	return err
+}
 
-			lastErr = err
-			logRetry(i, err)
-			time.Sleep(calculateDelay(i))
-			continue
+// attempt — одна попытка транзакции: begin, fn, commit. Возвращает ошибку и
+// признак того, что попытку стоит повторить.
+func (s *WalletServiceImpl) attempt(ctx context.Context, i int, fn func(context.Context, repository.WalletTx) error) (bool, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "wallet.tx.attempt",
+		trace.WithAttributes(attribute.Int("wallet.tx.attempt", i+1)))
+	defer span.End()
+
+	fail := func(stage string, retriable bool, err, cause error) (bool, error) {
+		span.SetAttributes(
+			attribute.String("wallet.tx.stage", stage),
+			attribute.Bool("wallet.tx.retriable", retriable),
+		)
+		var domainErr *Error
+		if !errors.As(cause, &domainErr) {
+			span.SetAttributes(semconv.DBResponseStatusCode(sqlState(cause)))
 		}
+		endSpan(span, cause)
+		return retriable, err
+	}
 
-		if err := tx.Commit(); err != nil {
-			if isRetriableError(err) {
-				lastErr = err
-				logRetry(i, err)
-				time.Sleep(calculateDelay(i))
-				continue
-			}
-			return fmt.Errorf("commit failed: %w", err)
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		if isRetriableError(err) {
+			return fail("begin", true, err, err)
 		}
-
-		return nil
+		return fail("begin", false, fmt.Errorf("%w: non-retriable begin error: %w", ErrStorageUnavailable, err), err)
 	}
 
-	return fmt.Errorf("max retries (%d) reached. Last error: %w", maxRetries, lastErr)
-}
+	if err := fn(ctx, tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.FromContext(ctx).Errorf("Rollback failed: %v", rbErr)
+		}
 
-func createWallet(tx *sql.Tx, walletID string, balance decimal.Decimal) error {
-	_, err := uuid.Parse(walletID)
-	if err != nil {
-		walletID = uuid.NewString()
+		if !isRetriableError(err) {
+			return fail("execute", false, fmt.Errorf("non-retriable error: %w", err), err)
+		}
+		return fail("execute", true, err, err)
+	}
 
+	if err := tx.Commit(); err != nil {
+		if isRetriableError(err) {
+			return fail("commit", true, err, err)
+		}
+		return fail("commit", false, fmt.Errorf("commit failed: %w", err), err)
 	}
-	logger.Log.Infof("Создание нового кошелька: wallet_id=%s, balance=%s", walletID, balance)
+	return false, nil
+}
 
-	queryInsert := `
-    INSERT INTO wallet_db (wallet_id, balance)
-    VALUES ($1, $2)`
-	_, err = tx.Exec(queryInsert, walletID, balance)
+// endSpan помечает спан ошибкой. Доменные ошибки (недостаточно средств,
+// кошелек заморожен) — ожидаемый исход: они записываются событием, но не
+// переводят спан в статус Error, в отличие от сбоев хранилища.
+func endSpan(span trace.Span, err error) {
+	span.RecordError(err)
+	var domainErr *Error
+	if errors.As(err, &domainErr) && !errors.Is(err, ErrRetriesExhausted) && !errors.Is(err, ErrStorageUnavailable) {
+		return
+	}
+	span.SetStatus(codes.Error, err.Error())
+}
 
-	return err
+// normalizeCurrency приводит код валюты к верхнему регистру и проверяет,
+// что валюта поддерживается. Пустой код остается пустым.
+func normalizeCurrency(code string) (string, error) {
+	code = currency.Normalize(code)
+	if code != "" && !currency.Supported(code) {
+		return "", ErrInvalidCurrency
+	}
+	return code, nil
+}
 
+// checkCurrency проверяет, что операция в валюте code применима к кошельку и
+// что amount укладывается в точность его валюты.
+func checkCurrency(wallet model.Wallet, code string, amount decimal.Decimal) error {
+	if code != "" && code != wallet.Currency {
+		return ErrCurrencyMismatch
+	}
+	if !currency.ValidAmount(wallet.Currency, amount) {
+		return ErrInvalidAmountScale
+	}
+	return nil
 }
 
 func calculateDelay(attempt int) time.Duration {
 	return time.Duration(attempt+1) * retryDelayBase
 }
 
-func logRetry(attempt int, err error) {
-	logger.Log.Warnf("Retry attempt %d/%d. Reason: %v",
+func logRetry(ctx context.Context, attempt int, err error) {
+	metrics.TxRetries.WithLabelValues(sqlState(err)).Inc()
+	logger.FromContext(ctx).Warnf("Retry attempt %d/%d. Reason: %v",
 		attempt+1,
 		maxRetries,
 		err,
 	)
 }
+func observeOperation(operationType string, amount decimal.Decimal, err error) {
+	if err != nil {
+		metrics.Operations.WithLabelValues(operationType, ErrorCode(err)).Inc()
+		return
+	}
+	metrics.Operations.WithLabelValues(operationType, "success").Inc()
+	metrics.OperationAmount.WithLabelValues(operationType).Add(amount.InexactFloat64())
+}
+
+// sqlState возвращает SQLSTATE ошибки PostgreSQL для меток метрик.
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	if isNetError(err) {
+		return "network"
+	}
+	return "unknown"
+}
+
 func isNetError(err error) bool {
 	var netErr net.Error
 	return errors.As(err, &netErr) && netErr.Timeout()
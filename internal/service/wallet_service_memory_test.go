@@ -0,0 +1,947 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/rates"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const (
+	walletA = "550e8400-e29b-41d4-a716-446655440000"
+	walletB = "c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b"
+)
+
+// flakyRepository возвращает ошибку сериализации на первых failures вызовах BeginTx.
+type flakyRepository struct {
+	repository.WalletRepository
+	failures int
+	calls    int
+}
+
+func (r *flakyRepository) BeginTx(ctx context.Context) (repository.WalletTx, error) {
+	r.calls++
+	if r.calls <= r.failures {
+		return nil, &pgconn.PgError{Code: serializationError}
+	}
+	return r.WalletRepository.BeginTx(ctx)
+}
+
+func statusUpdate(status string) UpdateWalletParams {
+	return UpdateWalletParams{Status: &status}
+}
+
+// openWallet создает кошелек и пополняет его на balance.
+func openWallet(t *testing.T, svc *WalletServiceImpl, walletID, currencyCode string, balance decimal.Decimal) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := svc.CreateWallet(ctx, CreateWalletParams{WalletID: walletID, Currency: currencyCode})
+	require.NoError(t, err)
+	if balance.IsPositive() {
+		_, err = svc.Deposit(ctx, walletID, balance, "")
+		require.NoError(t, err)
+	}
+}
+
+func TestMemory_ConcurrentDepositsAndWithdrawals(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+	openWallet(t, svc, walletA, "", decimal.NewFromInt(1000))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Deposit(ctx, walletA, decimal.NewFromInt(3), "")
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := svc.Withdraw(ctx, walletA, decimal.NewFromInt(2), "")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	wallet, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(1100)), "balance %s", wallet.Balance)
+
+	transactions, err := svc.GetTransactions(ctx, walletA, 500, 0)
+	require.NoError(t, err)
+	assert.Len(t, transactions, 201)
+}
+
+func TestMemory_TransferIsAtomic(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+	openWallet(t, svc, walletA, "", decimal.NewFromInt(100))
+	openWallet(t, svc, walletB, "", decimal.NewFromInt(100))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.Transfer(ctx, walletA, walletB, decimal.NewFromInt(7), "")
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = svc.Transfer(ctx, walletB, walletA, decimal.NewFromInt(5), "")
+		}()
+	}
+	wg.Wait()
+
+	a, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	b, err := svc.GetBalance(ctx, walletB)
+	require.NoError(t, err)
+	assert.True(t, a.Balance.Add(b.Balance).Equal(decimal.NewFromInt(200)))
+	assert.False(t, a.Balance.IsNegative())
+	assert.False(t, b.Balance.IsNegative())
+
+	_, err = svc.Transfer(ctx, walletA, walletB, decimal.NewFromInt(1000), "")
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	after, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, after.Balance.Equal(a.Balance))
+}
+
+func TestMemory_IdempotentDeposit(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	openWallet(t, svc, walletA, "", decimal.Zero)
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+
+	first, err := svc.Deposit(ctx, walletA, decimal.NewFromInt(100), "")
+	require.NoError(t, err)
+	second, err := svc.Deposit(ctx, walletA, decimal.NewFromInt(100), "")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+
+	_, err = svc.Deposit(ctx, walletA, decimal.NewFromInt(50), "")
+	assert.True(t, errors.Is(err, ErrIdempotencyKeyConflict))
+
+	wallet, err := svc.GetBalance(context.Background(), walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(100)))
+}
+
+func TestMemory_RetriesRetriableErrors(t *testing.T) {
+	memory := repository.NewMemoryRepository()
+	openWallet(t, NewWalletService(memory, nil), walletA, "", decimal.Zero)
+	repo := &flakyRepository{WalletRepository: memory, failures: 2}
+	svc := NewWalletService(repo, nil)
+
+	_, err := svc.Deposit(context.Background(), walletA, decimal.NewFromInt(10), "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, repo.calls)
+}
+
+func TestMemory_AsyncOperations(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	op, created, err := svc.CreateOperation(ctx, model.Operation{
+		WalletID:       walletA,
+		OperationType:  model.OperationWithdraw,
+		Amount:         decimal.NewFromInt(10),
+		IdempotencyKey: "async-1",
+	})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, model.OperationStatusPending, op.Status)
+
+	replay, created, err := svc.CreateOperation(ctx, model.Operation{
+		WalletID:       walletA,
+		OperationType:  model.OperationWithdraw,
+		Amount:         decimal.NewFromInt(10),
+		IdempotencyKey: "async-1",
+	})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, op.ID, replay.ID)
+
+	failed := svc.executeOperation(ctx, op)
+	assert.Equal(t, model.OperationStatusFailed, failed.Status)
+	assert.Equal(t, ErrWalletNotFound.Code, failed.ErrorCode)
+
+	openWallet(t, svc, walletA, "", decimal.Zero)
+
+	deposit, _, err := svc.CreateOperation(ctx, model.Operation{
+		WalletID:      walletA,
+		OperationType: model.OperationDeposit,
+		Amount:        decimal.NewFromInt(25),
+	})
+	require.NoError(t, err)
+	pool := NewWorkerPool(svc, 1, 1)
+	require.True(t, pool.AddJob(Job{Operation: deposit, Ctx: ctx}))
+	pool.Shutdown()
+
+	stored, err := svc.GetOperation(ctx, deposit.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OperationStatusSucceeded, stored.Status)
+	assert.NotEmpty(t, stored.TransactionID)
+
+	_, err = svc.GetOperation(ctx, "6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	assert.True(t, errors.Is(err, ErrOperationNotFound))
+}
+
+func TestMemory_AsyncOperationRecovery(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+	openWallet(t, svc, walletA, "", decimal.Zero)
+
+	// Отклоненная из-за очереди операция при повторе с тем же ключом снова pending.
+	rejected, created, err := svc.CreateOperation(ctx, model.Operation{
+		WalletID:       walletA,
+		OperationType:  model.OperationDeposit,
+		Amount:         decimal.NewFromInt(10),
+		IdempotencyKey: "async-queue-full",
+	})
+	require.NoError(t, err)
+	require.True(t, created)
+	_, err = svc.FailOperation(ctx, rejected.ID, ErrQueueFull)
+	require.NoError(t, err)
+
+	retried, created, err := svc.CreateOperation(ctx, model.Operation{
+		WalletID:       walletA,
+		OperationType:  model.OperationDeposit,
+		Amount:         decimal.NewFromInt(10),
+		IdempotencyKey: "async-queue-full",
+	})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, rejected.ID, retried.ID)
+	assert.Equal(t, model.OperationStatusPending, retried.Status)
+	assert.Empty(t, retried.ErrorCode)
+
+	// Операция, не попавшая в очередь до остановки, подхватывается при старте.
+	orphan, _, err := svc.CreateOperation(ctx, model.Operation{
+		WalletID:      walletA,
+		OperationType: model.OperationDeposit,
+		Amount:        decimal.NewFromInt(5),
+	})
+	require.NoError(t, err)
+
+	pool := NewWorkerPool(svc, 1, 1)
+	queued, err := pool.RequeuePending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued)
+	pool.Shutdown()
+
+	for _, id := range []string{retried.ID, orphan.ID} {
+		stored, err := svc.GetOperation(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, model.OperationStatusSucceeded, stored.Status)
+	}
+	wallet, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(15)))
+}
+
+func TestMemory_Holds(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	openWallet(t, svc, walletA, "", decimal.NewFromInt(100))
+	openWallet(t, svc, walletB, "", decimal.NewFromInt(1))
+
+	hold, err := svc.PlaceHold(ctx, walletA, decimal.NewFromInt(60), time.Hour)
+	require.NoError(t, err)
+	_, err = svc.PlaceHold(ctx, walletA, decimal.NewFromInt(50), time.Hour)
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+
+	_, err = svc.Withdraw(ctx, walletA, decimal.NewFromInt(41), "")
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	_, err = svc.Transfer(ctx, walletA, walletB, decimal.NewFromInt(41), "")
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+
+	_, err = svc.CaptureHold(ctx, hold.ID, decimal.NewFromInt(61))
+	assert.True(t, errors.Is(err, ErrCaptureExceedsHold))
+
+	capture, err := svc.CaptureHold(ctx, hold.ID, decimal.NewFromInt(45))
+	require.NoError(t, err)
+	assert.Equal(t, model.OperationCapture, capture.OperationType)
+	assert.True(t, capture.BalanceAfter.Equal(decimal.NewFromInt(55)))
+
+	_, err = svc.ReleaseHold(ctx, hold.ID)
+	assert.True(t, errors.Is(err, ErrHoldNotActive))
+
+	wallet, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.NewFromInt(55)))
+	assert.True(t, wallet.HeldBalance.IsZero())
+
+	released, err := svc.PlaceHold(ctx, walletA, decimal.NewFromInt(20), time.Hour)
+	require.NoError(t, err)
+	released, err = svc.ReleaseHold(ctx, released.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.HoldStatusReleased, released.Status)
+
+	wallet, err = svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.Available().Equal(decimal.NewFromInt(55)))
+}
+
+func TestMemory_ReleaseExpiredHolds(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	openWallet(t, svc, walletA, "", decimal.NewFromInt(100))
+	expiring, err := svc.PlaceHold(ctx, walletA, decimal.NewFromInt(30), time.Minute)
+	require.NoError(t, err)
+	_, err = svc.PlaceHold(ctx, walletA, decimal.NewFromInt(20), time.Hour)
+	require.NoError(t, err)
+
+	count, err := svc.ReleaseExpiredHolds(ctx, time.Now().Add(2*time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	hold, err := svc.GetHold(ctx, expiring.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.HoldStatusExpired, hold.Status)
+
+	wallet, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.HeldBalance.Equal(decimal.NewFromInt(20)))
+}
+
+func TestMemory_Currencies(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	openWallet(t, svc, walletA, "usd", decimal.NewFromInt(100))
+	openWallet(t, svc, walletB, "JPY", decimal.NewFromInt(1000))
+
+	wallet, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", wallet.Currency)
+
+	_, err = svc.Deposit(ctx, walletA, decimal.NewFromInt(1), "EUR")
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+	_, err = svc.Deposit(ctx, walletA, decimal.NewFromInt(1), "XXX")
+	assert.True(t, errors.Is(err, ErrInvalidCurrency))
+	_, err = svc.Withdraw(ctx, walletA, decimal.RequireFromString("0.001"), "")
+	assert.True(t, errors.Is(err, ErrInvalidAmountScale))
+	_, err = svc.Withdraw(ctx, walletB, decimal.RequireFromString("0.5"), "JPY")
+	assert.True(t, errors.Is(err, ErrInvalidAmountScale))
+	_, err = svc.Transfer(ctx, walletA, walletB, decimal.NewFromInt(1), "")
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	_, err = svc.Withdraw(ctx, walletA, decimal.RequireFromString("0.50"), "")
+	require.NoError(t, err)
+	wallet, err = svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.Equal(decimal.RequireFromString("99.5")))
+}
+
+func TestMemory_Convert(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	provider, err := rates.NewStatic(map[string]decimal.Decimal{
+		"USD/RUB": decimal.RequireFromString("92.3456"),
+	})
+	require.NoError(t, err)
+	svc := NewWalletService(repo, provider)
+	ctx := context.Background()
+
+	openWallet(t, svc, walletA, "USD", decimal.NewFromInt(100))
+	openWallet(t, svc, walletB, "RUB", decimal.NewFromInt(10))
+
+	quote, err := svc.CreateQuote(ctx, "usd", "rub", 0)
+	require.NoError(t, err)
+	assert.True(t, quote.Rate.Equal(decimal.RequireFromString("92.3456")))
+
+	keyed := WithIdempotencyKey(ctx, "convert-1")
+	conversion, err := svc.Convert(keyed, walletA, walletB, decimal.RequireFromString("10.01"), quote.ID)
+	require.NoError(t, err)
+	assert.True(t, conversion.CreditAmount.Equal(decimal.RequireFromString("924.37")))
+	assert.True(t, conversion.Remainder.Equal(decimal.RequireFromString("0.009456")))
+	assert.Equal(t, quote.ID, conversion.QuoteID)
+
+	replay, err := svc.Convert(keyed, walletA, walletB, decimal.RequireFromString("10.01"), quote.ID)
+	require.NoError(t, err)
+	assert.Equal(t, conversion.ID, replay.ID)
+
+	a, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, a.Balance.Equal(decimal.RequireFromString("89.99")))
+	b, err := svc.GetBalance(ctx, walletB)
+	require.NoError(t, err)
+	assert.True(t, b.Balance.Equal(decimal.RequireFromString("934.37")))
+
+	_, err = svc.Convert(ctx, walletB, walletA, decimal.NewFromInt(1000), "")
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	_, err = svc.Convert(ctx, walletB, walletA, decimal.NewFromInt(10), quote.ID)
+	assert.True(t, errors.Is(err, ErrCurrencyMismatch))
+
+	expired, err := repo.InsertQuote(ctx, model.Quote{
+		ID:           "6ba7b812-9dad-11d1-80b4-00c04fd430c8",
+		FromCurrency: "USD",
+		ToCurrency:   "RUB",
+		Rate:         decimal.NewFromInt(1),
+		ExpiresAt:    time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+	_, err = svc.Convert(ctx, walletA, walletB, decimal.NewFromInt(1), expired.ID)
+	assert.True(t, errors.Is(err, ErrQuoteExpired))
+
+	_, err = NewWalletService(repo, nil).CreateQuote(ctx, "USD", "RUB", 0)
+	assert.True(t, errors.Is(err, ErrRateNotAvailable))
+}
+
+func TestMemory_WalletLifecycle(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	_, err := svc.Deposit(ctx, walletA, decimal.NewFromInt(10), "")
+	assert.True(t, errors.Is(err, ErrWalletNotFound))
+
+	openWallet(t, svc, walletA, "", decimal.NewFromInt(10))
+	openWallet(t, svc, walletB, "", decimal.Zero)
+	_, err = svc.CreateWallet(ctx, CreateWalletParams{WalletID: walletA})
+	assert.True(t, errors.Is(err, ErrWalletExists))
+
+	generated, err := svc.CreateWallet(ctx, CreateWalletParams{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, generated.WalletID)
+	assert.Equal(t, "RUB", generated.Currency)
+
+	_, err = svc.UpdateWallet(ctx, walletA, statusUpdate(model.WalletStatusFrozen))
+	require.NoError(t, err)
+	_, err = svc.Withdraw(ctx, walletA, decimal.NewFromInt(1), "")
+	assert.True(t, errors.Is(err, ErrWalletFrozen))
+	_, err = svc.Transfer(ctx, walletA, walletB, decimal.NewFromInt(1), "")
+	assert.True(t, errors.Is(err, ErrWalletFrozen))
+	_, err = svc.Deposit(ctx, walletA, decimal.NewFromInt(5), "")
+	assert.NoError(t, err)
+
+	_, err = svc.UpdateWallet(ctx, walletA, statusUpdate(model.WalletStatusClosed))
+	assert.True(t, errors.Is(err, ErrWalletNotEmpty))
+	_, err = svc.UpdateWallet(ctx, walletA, statusUpdate("deleted"))
+	assert.True(t, errors.Is(err, ErrInvalidWalletStatus))
+
+	closed, err := svc.UpdateWallet(ctx, walletB, statusUpdate(model.WalletStatusClosed))
+	require.NoError(t, err)
+	assert.Equal(t, model.WalletStatusClosed, closed.Status)
+	_, err = svc.Deposit(ctx, walletB, decimal.NewFromInt(1), "")
+	assert.True(t, errors.Is(err, ErrWalletClosed))
+	_, err = svc.UpdateWallet(ctx, walletB, statusUpdate(model.WalletStatusActive))
+	assert.True(t, errors.Is(err, ErrWalletClosed))
+}
+
+func TestMemory_WalletOwnerAndMetadata(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	created, err := svc.CreateWallet(ctx, CreateWalletParams{
+		WalletID: walletA,
+		OwnerID:  "user-42",
+		Metadata: map[string]any{"tier": "gold"},
+		Labels:   []string{" savings ", "savings", "family"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", created.OwnerID)
+	assert.Equal(t, []string{"savings", "family"}, created.Labels)
+
+	_, err = svc.CreateWallet(ctx, CreateWalletParams{WalletID: walletB, OwnerID: "user-42", Currency: "USD"})
+	require.NoError(t, err)
+	_, err = svc.CreateWallet(ctx, CreateWalletParams{OwnerID: "user-7"})
+	require.NoError(t, err)
+
+	wallets, err := svc.ListWalletsByOwner(ctx, "user-42")
+	require.NoError(t, err)
+	require.Len(t, wallets, 2)
+	assert.Equal(t, walletA, wallets[0].WalletID)
+	assert.Equal(t, walletB, wallets[1].WalletID)
+
+	_, err = svc.ListWalletsByOwner(ctx, "")
+	assert.True(t, errors.Is(err, ErrInvalidOwnerID))
+	_, err = svc.CreateWallet(ctx, CreateWalletParams{Labels: []string{""}})
+	assert.True(t, errors.Is(err, ErrInvalidLabels))
+	_, err = svc.CreateWallet(ctx, CreateWalletParams{Metadata: map[string]any{"blob": strings.Repeat("x", maxMetadataSize)}})
+	assert.True(t, errors.Is(err, ErrInvalidMetadata))
+
+	owner := "user-7"
+	updated, err := svc.UpdateWallet(ctx, walletA, UpdateWalletParams{OwnerID: &owner, Labels: []string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "user-7", updated.OwnerID)
+	assert.Nil(t, updated.Labels)
+	assert.Equal(t, map[string]any{"tier": "gold"}, updated.Metadata)
+	assert.Equal(t, model.WalletStatusActive, updated.Status)
+
+	wallets, err = svc.ListWalletsByOwner(ctx, "user-42")
+	require.NoError(t, err)
+	assert.Len(t, wallets, 1)
+	wallets, err = svc.ListWalletsByOwner(ctx, "user-7")
+	require.NoError(t, err)
+	assert.Len(t, wallets, 2)
+}
+
+func TestMemory_ListWalletsPagination(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		wallet, err := svc.CreateWallet(ctx, CreateWalletParams{OwnerID: "back-office"})
+		require.NoError(t, err)
+		_, err = svc.Deposit(ctx, wallet.WalletID, decimal.NewFromInt(int64(i*10)), "")
+		require.NoError(t, err)
+	}
+	frozen, err := svc.CreateWallet(ctx, CreateWalletParams{})
+	require.NoError(t, err)
+	_, err = svc.UpdateWallet(ctx, frozen.WalletID, statusUpdate(model.WalletStatusFrozen))
+	require.NoError(t, err)
+
+	var balances []string
+	query := WalletQuery{OwnerID: "back-office", SortBy: "balance", Desc: true, Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		page, err := svc.ListWallets(ctx, query)
+		require.NoError(t, err)
+		for _, wallet := range page.Wallets {
+			balances = append(balances, wallet.Balance.String())
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"50", "40", "30", "20", "10"}, balances)
+
+	_, err = svc.ListWallets(ctx, WalletQuery{SortBy: "createdAt", Cursor: query.Cursor})
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+
+	minBalance, maxBalance := decimal.NewFromInt(20), decimal.NewFromInt(40)
+	page, err := svc.ListWallets(ctx, WalletQuery{MinBalance: &minBalance, MaxBalance: &maxBalance})
+	require.NoError(t, err)
+	assert.Len(t, page.Wallets, 3)
+	assert.Empty(t, page.NextCursor)
+
+	page, err = svc.ListWallets(ctx, WalletQuery{Status: model.WalletStatusFrozen})
+	require.NoError(t, err)
+	require.Len(t, page.Wallets, 1)
+	assert.Equal(t, frozen.WalletID, page.Wallets[0].WalletID)
+
+	page, err = svc.ListWallets(ctx, WalletQuery{CreatedFrom: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, page.Wallets)
+}
+
+// tamperedAudit отдает журнал, испорченный функцией tamper, как если бы
+// записи изменили прямо в базе.
+type tamperedAudit struct {
+	repository.AuditRepository
+	tamper func([]model.AuditEntry) []model.AuditEntry
+}
+
+func (r tamperedAudit) ListAudit(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	entries, err := r.AuditRepository.ListAudit(ctx, filter)
+	return r.tamper(entries), err
+}
+
+func TestMemory_AuditChain(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	audit := NewAuditService(repo)
+
+	before, after := decimal.NewFromInt(100), decimal.NewFromInt(70)
+	for _, action := range []string{"wallet.create", "wallet.withdraw", "wallet.update"} {
+		_, err := audit.Record(ctx, model.AuditEntry{
+			Actor: "backoffice", Action: action, WalletID: walletA,
+			BalanceBefore: &before, BalanceAfter: &after,
+			ClientIP: "10.0.0.1", RequestID: "req-" + action, Result: model.AuditResultSuccess,
+		})
+		require.NoError(t, err)
+	}
+
+	page, err := audit.ListAudit(ctx, AuditQuery{WalletID: walletA, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+	assert.Empty(t, page.Entries[0].PrevHash)
+	assert.Equal(t, page.Entries[0].Hash, page.Entries[1].PrevHash)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = audit.ListAudit(ctx, AuditQuery{WalletID: walletA, Cursor: page.NextCursor, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, "wallet.update", page.Entries[0].Action)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = audit.ListAudit(ctx, AuditQuery{Cursor: "abc"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	result, err := audit.VerifyAudit(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, 3, result.Checked)
+
+	testCases := []struct {
+		name     string
+		tamper   func([]model.AuditEntry) []model.AuditEntry
+		brokenAt int64
+	}{
+		{"modified", func(entries []model.AuditEntry) []model.AuditEntry {
+			if len(entries) > 1 {
+				entries[1].Result = "INSUFFICIENT_FUNDS"
+			}
+			return entries
+		}, 2},
+		{"deleted", func(entries []model.AuditEntry) []model.AuditEntry {
+			if len(entries) > 1 {
+				return append(entries[:1:1], entries[2:]...)
+			}
+			return entries
+		}, 3},
+	}
+	for _, tc := range testCases {
+		result, err := NewAuditService(tamperedAudit{repo, tc.tamper}).VerifyAudit(ctx)
+		require.NoError(t, err)
+		assert.False(t, result.Valid, tc.name)
+		assert.Equal(t, tc.brokenAt, result.BrokenAtID, tc.name)
+	}
+}
+
+func TestMemory_BalanceHistory(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	openWallet(t, svc, walletA, "", decimal.NewFromInt(100))
+	wallet, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	between := time.Now()
+	time.Sleep(time.Millisecond)
+	_, err = svc.Withdraw(ctx, walletA, decimal.NewFromInt(40), "")
+	require.NoError(t, err)
+
+	balance, err := svc.BalanceAt(ctx, walletA, between)
+	require.NoError(t, err)
+	assert.True(t, balance.Balance.Equal(decimal.NewFromInt(100)), "balance %s", balance.Balance)
+	assert.Equal(t, wallet.Currency, balance.Currency)
+
+	balance, err = svc.BalanceAt(ctx, walletA, time.Now())
+	require.NoError(t, err)
+	assert.True(t, balance.Balance.Equal(decimal.NewFromInt(60)), "balance %s", balance.Balance)
+
+	_, err = svc.BalanceAt(ctx, walletA, wallet.CreatedAt.Add(-time.Second))
+	assert.ErrorIs(t, err, ErrBalanceBeforeCreation)
+	_, err = svc.BalanceAt(ctx, walletA, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidBalanceTime)
+
+	today := time.Now().UTC()
+	_, err = svc.DailyBalances(ctx, walletA, today, today.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+	_, err = svc.DailyBalances(ctx, walletA, today.AddDate(-2, 0, 0), today)
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+
+	// Без снимков баланс дня берется из транзакций, дни до создания кошелька
+	// пропускаются.
+	series, err := svc.DailyBalances(ctx, walletA, today.AddDate(0, 0, -7), today)
+	require.NoError(t, err)
+	require.Len(t, series.Days, 1)
+	assert.Equal(t, today.Format(model.DateLayout), series.Days[0].Date)
+	assert.True(t, series.Days[0].Balance.Equal(decimal.NewFromInt(60)))
+
+	// После снимка тот же день читается из снимка.
+	saved, err := svc.SnapshotBalances(ctx, today.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved)
+	saved, err = svc.SnapshotBalances(ctx, today.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, saved)
+
+	snapshotted, err := svc.DailyBalances(ctx, walletA, today.AddDate(0, 0, -7), today)
+	require.NoError(t, err)
+	assert.Equal(t, series, snapshotted)
+}
+
+// Кошелек, созданный до журнала транзакций, имеет баланс без единой
+// транзакции: его история равна текущему балансу, а не нулю.
+func TestMemory_BalanceHistoryWithoutTransactions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := NewWalletService(repo, nil)
+	ctx := context.Background()
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	for _, walletID := range []string{walletA, walletB} {
+		_, err = tx.InsertWallet(ctx, model.Wallet{WalletID: walletID, Currency: "RUB", Balance: decimal.NewFromInt(100)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	time.Sleep(time.Millisecond)
+	balance, err := svc.BalanceAt(ctx, walletA, time.Now())
+	require.NoError(t, err)
+	assert.True(t, balance.Balance.Equal(decimal.NewFromInt(100)), "balance %s", balance.Balance)
+
+	today := time.Now().UTC()
+	series, err := svc.DailyBalances(ctx, walletA, today.AddDate(0, 0, -7), today)
+	require.NoError(t, err)
+	require.Len(t, series.Days, 1)
+	assert.True(t, series.Days[0].Balance.Equal(decimal.NewFromInt(100)), "balance %s", series.Days[0].Balance)
+
+	// После первой транзакции момент до нее дает баланс до этой транзакции.
+	between := time.Now()
+	time.Sleep(time.Millisecond)
+	_, err = svc.Withdraw(ctx, walletA, decimal.NewFromInt(40), "")
+	require.NoError(t, err)
+
+	balance, err = svc.BalanceAt(ctx, walletA, between)
+	require.NoError(t, err)
+	assert.True(t, balance.Balance.Equal(decimal.NewFromInt(100)), "balance %s", balance.Balance)
+
+	// Снимков у кошелька без транзакций нет, дни из снимков тоже берут
+	// баланс до первой транзакции.
+	saved, err := svc.SnapshotBalances(ctx, today.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved)
+	series, err = svc.DailyBalances(ctx, walletB, today.AddDate(0, 0, -7), today)
+	require.NoError(t, err)
+	require.Len(t, series.Days, 1)
+	assert.True(t, series.Days[0].Balance.Equal(decimal.NewFromInt(100)), "balance %s", series.Days[0].Balance)
+}
+
+// statementRecorder собирает выписку, переданную StatementWriter.
+type statementRecorder struct {
+	header     model.StatementHeader
+	operations []model.Transaction
+	summary    model.StatementSummary
+}
+
+func (s *statementRecorder) Begin(header model.StatementHeader) error {
+	s.header = header
+	return nil
+}
+
+func (s *statementRecorder) Operation(t model.Transaction) error {
+	s.operations = append(s.operations, t)
+	return nil
+}
+
+func (s *statementRecorder) End(summary model.StatementSummary) error {
+	s.summary = summary
+	return nil
+}
+
+func TestMemory_Statement(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+
+	openWallet(t, svc, walletA, "", decimal.NewFromInt(100))
+	openWallet(t, svc, walletB, "", decimal.Zero)
+	_, err := svc.Withdraw(ctx, walletA, decimal.NewFromInt(30), "")
+	require.NoError(t, err)
+	_, err = svc.Transfer(ctx, walletA, walletB, decimal.NewFromInt(20), "")
+	require.NoError(t, err)
+
+	today := time.Now().UTC()
+	var rec statementRecorder
+	require.NoError(t, svc.Statement(ctx, walletA, today.AddDate(0, 0, -1), today, &rec))
+	assert.True(t, rec.header.OpeningBalance.IsZero())
+	assert.Equal(t, startOfDay(today).AddDate(0, 0, 1), rec.header.To)
+	require.Len(t, rec.operations, 3)
+	assert.Equal(t, model.OperationDeposit, rec.operations[0].OperationType)
+	assert.Equal(t, model.OperationTransfer, rec.operations[2].OperationType)
+	assert.Equal(t, 3, rec.summary.OperationCount)
+	assert.True(t, rec.summary.ClosingBalance.Equal(decimal.NewFromInt(50)), "closing %s", rec.summary.ClosingBalance)
+	assert.True(t, rec.summary.TotalCredits.Equal(decimal.NewFromInt(100)))
+	assert.True(t, rec.summary.TotalDebits.Equal(decimal.NewFromInt(50)))
+
+	// Период до сегодняшних операций: пустая выписка с нулевыми балансами.
+	rec = statementRecorder{}
+	require.NoError(t, svc.Statement(ctx, walletA, today.AddDate(0, 0, -3), today.AddDate(0, 0, -2), &rec))
+	assert.Empty(t, rec.operations)
+	assert.True(t, rec.summary.ClosingBalance.IsZero())
+
+	rec = statementRecorder{}
+	err = svc.Statement(ctx, walletA, today, today.AddDate(0, 0, -1), &rec)
+	assert.ErrorIs(t, err, ErrInvalidDateRange)
+	assert.Empty(t, rec.header.WalletID, "Begin must not be called on validation errors")
+}
+
+// Выписка кошелька, созданного до журнала транзакций, начинается с его
+// баланса, а не с нуля.
+func TestMemory_StatementWithoutTransactions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	svc := NewWalletService(repo, nil)
+	ctx := context.Background()
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	_, err = tx.InsertWallet(ctx, model.Wallet{WalletID: walletA, Currency: "RUB", Balance: decimal.NewFromInt(100)})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	today := time.Now().UTC()
+	var rec statementRecorder
+	require.NoError(t, svc.Statement(ctx, walletA, today.AddDate(0, 0, -1), today, &rec))
+	assert.True(t, rec.header.OpeningBalance.Equal(decimal.NewFromInt(100)), "opening %s", rec.header.OpeningBalance)
+	assert.Empty(t, rec.operations)
+	assert.True(t, rec.summary.ClosingBalance.Equal(decimal.NewFromInt(100)), "closing %s", rec.summary.ClosingBalance)
+
+	_, err = svc.Withdraw(ctx, walletA, decimal.NewFromInt(30), "")
+	require.NoError(t, err)
+
+	rec = statementRecorder{}
+	require.NoError(t, svc.Statement(ctx, walletA, today.AddDate(0, 0, -1), today, &rec))
+	assert.True(t, rec.header.OpeningBalance.Equal(decimal.NewFromInt(100)), "opening %s", rec.header.OpeningBalance)
+	require.Len(t, rec.operations, 1)
+	assert.True(t, rec.summary.ClosingBalance.Equal(decimal.NewFromInt(70)), "closing %s", rec.summary.ClosingBalance)
+}
+
+func TestMemory_LedgerTrialBalance(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	provider, err := rates.NewStatic(map[string]decimal.Decimal{
+		"USD/RUB": decimal.RequireFromString("92.3456"),
+	})
+	require.NoError(t, err)
+	svc := NewWalletService(repo, provider)
+	ctx := context.Background()
+	walletC := "6ba7b812-9dad-11d1-80b4-00c04fd430c8"
+
+	openWallet(t, svc, walletA, "RUB", decimal.NewFromInt(500))
+	openWallet(t, svc, walletB, "RUB", decimal.Zero)
+	openWallet(t, svc, walletC, "USD", decimal.NewFromInt(10))
+	_, err = svc.Withdraw(ctx, walletA, decimal.NewFromInt(50), "")
+	require.NoError(t, err)
+	_, err = svc.Transfer(ctx, walletA, walletB, decimal.NewFromInt(100), "")
+	require.NoError(t, err)
+	hold, err := svc.PlaceHold(ctx, walletB, decimal.NewFromInt(30), time.Minute)
+	require.NoError(t, err)
+	_, err = svc.CaptureHold(ctx, hold.ID, decimal.NewFromInt(20))
+	require.NoError(t, err)
+	_, err = svc.Convert(ctx, walletC, walletA, decimal.RequireFromString("1.01"), "")
+	require.NoError(t, err)
+
+	result, err := svc.TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Balanced)
+	assert.True(t, result.Reconciled)
+	assert.Empty(t, result.WalletMismatches)
+
+	balances := make(map[string]string)
+	for _, c := range result.Accounts {
+		balances[c.Kind+"/"+c.Currency] = c.Balance.String()
+	}
+	assert.Equal(t, map[string]string{
+		"wallet/RUB":   "523.26",
+		"cash_in/RUB":  "-500",
+		"cash_out/RUB": "70",
+		"fx/RUB":       "-93.26",
+		"wallet/USD":   "8.99",
+		"cash_in/USD":  "-10",
+		"fx/USD":       "1.01",
+	}, balances)
+	require.Len(t, result.Currencies, 2)
+	assert.Equal(t, "RUB", result.Currencies[0].Currency)
+	assert.True(t, result.Currencies[0].Debits.Equal(decimal.RequireFromString("593.26")))
+
+	// Несбалансированная проводка не сохраняется.
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	_, err = tx.InsertPosting(ctx, model.Posting{
+		ID:            "6ba7b813-9dad-11d1-80b4-00c04fd430c8",
+		OperationType: model.OperationDeposit,
+		Entries:       []model.LedgerEntry{{Account: model.WalletAccount(walletA, "RUB"), Amount: decimal.NewFromInt(1)}},
+	})
+	assert.ErrorIs(t, err, repository.ErrUnbalancedPosting)
+	require.NoError(t, tx.Rollback())
+}
+
+func TestMemory_Reversals(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryRepository(), nil)
+	ctx := context.Background()
+	openWallet(t, svc, walletA, "RUB", decimal.Zero)
+
+	deposit, err := svc.Deposit(ctx, walletA, decimal.NewFromInt(100), "")
+	require.NoError(t, err)
+	withdrawal, err := svc.Withdraw(ctx, walletA, decimal.NewFromInt(40), "")
+	require.NoError(t, err)
+
+	// Частичный возврат пополнения, повтор с тем же ключом не сторнирует второй раз.
+	keyed := WithIdempotencyKey(ctx, "refund-1")
+	refund, err := svc.Reverse(keyed, deposit.ID, decimal.NewFromInt(30))
+	require.NoError(t, err)
+	assert.Equal(t, model.OperationReversal, refund.OperationType)
+	assert.Equal(t, deposit.ID, refund.ReversalOf)
+	assert.True(t, refund.Amount.Equal(decimal.NewFromInt(-30)))
+	assert.True(t, refund.BalanceAfter.Equal(decimal.NewFromInt(30)))
+	replay, err := svc.Reverse(keyed, deposit.ID, decimal.NewFromInt(30))
+	require.NoError(t, err)
+	assert.Equal(t, refund.ID, replay.ID)
+
+	_, err = svc.Reverse(ctx, deposit.ID, decimal.NewFromInt(71))
+	assert.ErrorIs(t, err, ErrReversalExceedsAmount)
+	_, err = svc.Reverse(ctx, deposit.ID, decimal.Zero)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// Сторно списания возвращает деньги на кошелек.
+	undo, err := svc.Reverse(ctx, withdrawal.ID, decimal.Zero)
+	require.NoError(t, err)
+	assert.True(t, undo.Amount.Equal(decimal.NewFromInt(40)))
+	assert.True(t, undo.BalanceAfter.Equal(decimal.NewFromInt(70)))
+	_, err = svc.Reverse(ctx, withdrawal.ID, decimal.Zero)
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+
+	rest, err := svc.Reverse(ctx, deposit.ID, decimal.Zero)
+	require.NoError(t, err)
+	assert.True(t, rest.Amount.Equal(decimal.NewFromInt(-70)))
+	_, err = svc.Reverse(ctx, deposit.ID, decimal.NewFromInt(1))
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+
+	_, err = svc.Reverse(ctx, rest.ID, decimal.Zero)
+	assert.ErrorIs(t, err, ErrNotReversible)
+	_, err = svc.Reverse(ctx, "6ba7b813-9dad-11d1-80b4-00c04fd430c8", decimal.Zero)
+	assert.ErrorIs(t, err, ErrTransactionNotFound)
+	_, err = svc.Reverse(ctx, deposit.ID, decimal.NewFromInt(-1))
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	transactions, err := svc.GetTransactions(ctx, walletA, 10, 0)
+	require.NoError(t, err)
+	reversed := make(map[string]string)
+	for _, tr := range transactions {
+		reversed[tr.ID] = tr.ReversedAmount.String()
+	}
+	assert.Equal(t, "100", reversed[deposit.ID])
+	assert.Equal(t, "40", reversed[withdrawal.ID])
+
+	wallet, err := svc.GetBalance(ctx, walletA)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.IsZero())
+
+	// Сторно проводится обратно на исходный системный счет.
+	result, err := svc.TrialBalance(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Balanced)
+	assert.True(t, result.Reconciled)
+	for _, c := range result.Accounts {
+		assert.True(t, c.Balance.IsZero(), "%s/%s: %s", c.Kind, c.Currency, c.Balance)
+	}
+}
+
+func TestCanonicalAmount(t *testing.T) {
+	for input, expected := range map[string]string{
+		"100":     "100",
+		"100.00":  "100",
+		"100.50":  "100.5",
+		"-0.010":  "-0.01",
+		"1e2":     "100",
+		"0.00":    "0",
+		"12.3456": "12.3456",
+	} {
+		assert.Equal(t, expected, canonicalAmount(decimal.RequireFromString(input)), input)
+	}
+}
@@ -14,6 +14,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 // Test Suite
@@ -29,7 +37,7 @@ func (s *WalletServiceSuite) SetupTest() {
 	require.NoError(s.T(), err)
 	s.db = db
 	s.mock = mock
-	s.service = NewWalletService(db)
+	s.service = NewWalletService(repository.NewPostgresRepository(db), nil)
 }
 
 func (s *WalletServiceSuite) TearDownTest() {
@@ -40,27 +48,76 @@ func TestWalletServiceSuite(t *testing.T) {
 	suite.Run(t, new(WalletServiceSuite))
 }
 
-func (s *WalletServiceSuite) TestDeposit_NewWallet() {
+func (s *WalletServiceSuite) TestCreateWallet() {
 	walletID := "550e8400-e29b-41d4-a716-446655440000"
-	amount := decimal.NewFromFloat(100.50)
 
 	s.mock.ExpectBegin()
-	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
-		WithArgs(walletID).
-		WillReturnError(sql.ErrNoRows)
-	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO wallet_db (wallet_id, balance) VALUES ($1, $2)`)).
-		WithArgs(walletID, amount).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO wallet_db (wallet_id, balance, currency, status, owner_id, metadata, labels) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at, updated_at`)).
+		WithArgs(walletID, decimal.Zero, "USD", "active", "user-42", []byte(`{"tier":"gold"}`), []byte(`["savings"]`)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(time.Now(), time.Now()))
 	s.mock.ExpectCommit()
 
-	err := s.service.Deposit(context.Background(), walletID, amount)
+	wallet, err := s.service.CreateWallet(context.Background(), CreateWalletParams{
+		WalletID: walletID,
+		Currency: "usd",
+		OwnerID:  "user-42",
+		Metadata: map[string]any{"tier": "gold"},
+		Labels:   []string{"savings"},
+	})
 
 	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "USD", wallet.Currency)
+	assert.Equal(s.T(), "active", wallet.Status)
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestCreateWallet_Exists() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO wallet_db`)).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	s.mock.ExpectRollback()
+
+	_, err := s.service.CreateWallet(context.Background(), CreateWalletParams{WalletID: walletID})
+
+	assert.True(s.T(), errors.Is(err, ErrWalletExists))
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestDeposit_UnknownWallet() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(walletID).
+		WillReturnError(sql.ErrNoRows)
+	s.mock.ExpectRollback()
+
+	_, err := s.service.Deposit(context.Background(), walletID, decimal.NewFromFloat(100.50), "")
+
+	assert.True(s.T(), errors.Is(err, ErrWalletNotFound))
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestWithdraw_FrozenWallet() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(walletID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(decimal.NewFromInt(100), decimal.Zero, "RUB", "frozen", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+	s.mock.ExpectRollback()
+
+	_, err := s.service.Withdraw(context.Background(), walletID, decimal.NewFromInt(10), "")
+
+	assert.True(s.T(), errors.Is(err, ErrWalletFrozen))
 	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
 }
 
 func (s *WalletServiceSuite) TestDeposit_InvalidUUID() {
-	err := s.service.Deposit(context.Background(), "invalid-uuid", decimal.NewFromInt(100))
+	_, err := s.service.Deposit(context.Background(), "invalid-uuid", decimal.NewFromInt(100), "")
 
 	assert.Error(s.T(), err)
 	assert.Contains(s.T(), err.Error(), "invalid wallet ID format")
@@ -72,17 +129,170 @@ func (s *WalletServiceSuite) TestWithdraw_InsufficientFunds() {
 	withdrawAmount := decimal.NewFromInt(100)
 
 	s.mock.ExpectBegin()
-	rows := sqlmock.NewRows([]string{"balance", "created_at", "updated_at"}).
-		AddRow(initialBalance, time.Now(), time.Now())
-	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+	rows := sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+		AddRow(initialBalance, decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now())
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
 		WithArgs(walletID).
 		WillReturnRows(rows)
 	s.mock.ExpectRollback()
 
-	err := s.service.Withdraw(context.Background(), walletID, withdrawAmount)
+	_, err := s.service.Withdraw(context.Background(), walletID, withdrawAmount, "")
 
 	assert.Error(s.T(), err)
 	assert.Contains(s.T(), err.Error(), "insufficient funds")
+	assert.True(s.T(), errors.Is(err, ErrInsufficientFunds))
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestWithdraw_RecordsTransaction() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	initialBalance := decimal.NewFromInt(150)
+	withdrawAmount := decimal.NewFromInt(100)
+	expectedBalance := decimal.NewFromInt(50)
+
+	s.mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+		AddRow(initialBalance, decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now())
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(walletID).
+		WillReturnRows(rows)
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE wallet_db SET balance = $1, updated_at = NOW() WHERE wallet_id = $2`)).
+		WithArgs(expectedBalance, walletID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO wallet_transactions (id, wallet_id, amount, operation_type, balance_after, counterparty_wallet_id, reversal_of) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`)).
+		WithArgs(sqlmock.AnyArg(), walletID, withdrawAmount.Neg(), "WITHDRAW", expectedBalance, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_accounts (id, kind, currency, wallet_id) VALUES ($1, $2, $3, $4), ($5, $6, $7, $8) ON CONFLICT (id) DO NOTHING`)).
+		WithArgs(walletID, "wallet", "RUB", walletID, "system:cash_out:RUB", "cash_out", "RUB", nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO ledger_postings (id, operation_type) VALUES ($1, $2) RETURNING created_at`)).
+		WithArgs(sqlmock.AnyArg(), "WITHDRAW").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_entries (posting_id, account_id, currency, amount, transaction_id) VALUES ($1, $2, $3, $4, $5), ($1, $6, $7, $8, $9)`)).
+		WithArgs(sqlmock.AnyArg(), walletID, "RUB", withdrawAmount.Neg(), sqlmock.AnyArg(), "system:cash_out:RUB", "RUB", withdrawAmount, nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectCommit()
+
+	transaction, err := s.service.Withdraw(context.Background(), walletID, withdrawAmount, "")
+
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "WITHDRAW", transaction.OperationType)
+	assert.True(s.T(), transaction.BalanceAfter.Equal(expectedBalance))
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestCaptureHold_WholeBalance() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	holdID := "6ba7b812-9dad-11d1-80b4-00c04fd430c8"
+	amount := decimal.NewFromInt(100)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, wallet_id, amount, captured_amount, status, transaction_id, expires_at, created_at, updated_at FROM holds WHERE id = $1 FOR UPDATE`)).
+		WithArgs(holdID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "wallet_id", "amount", "captured_amount", "status", "transaction_id", "expires_at", "created_at", "updated_at"}).
+			AddRow(holdID, walletID, amount, decimal.Zero, "active", nil, time.Now().Add(time.Hour), time.Now(), time.Now()))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(walletID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(amount, amount, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+	// held_balance <= balance: холд снимается раньше, чем уменьшается баланс.
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE wallet_db SET held_balance = $1, updated_at = NOW() WHERE wallet_id = $2`)).
+		WithArgs(decimal.Zero, walletID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE wallet_db SET balance = $1, updated_at = NOW() WHERE wallet_id = $2`)).
+		WithArgs(decimal.Zero, walletID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO wallet_transactions (id, wallet_id, amount, operation_type, balance_after, counterparty_wallet_id, reversal_of) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`)).
+		WithArgs(sqlmock.AnyArg(), walletID, amount.Neg(), "CAPTURE", decimal.Zero, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_accounts (id, kind, currency, wallet_id) VALUES ($1, $2, $3, $4), ($5, $6, $7, $8) ON CONFLICT (id) DO NOTHING`)).
+		WithArgs(walletID, "wallet", "RUB", walletID, "system:cash_out:RUB", "cash_out", "RUB", nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO ledger_postings (id, operation_type) VALUES ($1, $2) RETURNING created_at`)).
+		WithArgs(sqlmock.AnyArg(), "CAPTURE").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_entries (posting_id, account_id, currency, amount, transaction_id) VALUES ($1, $2, $3, $4, $5), ($1, $6, $7, $8, $9)`)).
+		WithArgs(sqlmock.AnyArg(), walletID, "RUB", amount.Neg(), sqlmock.AnyArg(), "system:cash_out:RUB", "RUB", amount, nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`UPDATE holds SET status = $1, captured_amount = $2, transaction_id = $3, updated_at = NOW() WHERE id = $4 RETURNING updated_at`)).
+		WithArgs("captured", amount, sqlmock.AnyArg(), holdID).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	s.mock.ExpectCommit()
+
+	transaction, err := s.service.CaptureHold(context.Background(), holdID, decimal.Zero)
+
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), transaction.BalanceAfter.IsZero())
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestDeposit_IdempotentReplay() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	amount := decimal.NewFromInt(100)
+	key := "retry-key-1"
+	transactionID := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO idempotency_keys (key, request_hash) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`)).
+		WithArgs(key, requestHash(walletID, "DEPOSIT", amount.String(), "")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT request_hash, transaction_id FROM idempotency_keys WHERE key = $1`)).
+		WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "transaction_id"}).
+			AddRow(requestHash(walletID, "DEPOSIT", amount.String(), ""), transactionID))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT t.id, t.wallet_id, t.amount, t.operation_type, t.balance_after, t.counterparty_wallet_id, t.reversal_of, (SELECT COALESCE(SUM(ABS(r.amount)), 0) FROM wallet_transactions r WHERE r.reversal_of = t.id), t.created_at FROM wallet_transactions t WHERE t.id = $1`)).
+		WithArgs(transactionID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "wallet_id", "amount", "operation_type", "balance_after", "counterparty_wallet_id", "reversal_of", "reversed_amount", "created_at"}).
+			AddRow(transactionID, walletID, amount, "DEPOSIT", amount, nil, nil, decimal.Zero, time.Now()))
+	s.mock.ExpectCommit()
+
+	ctx := WithIdempotencyKey(context.Background(), key)
+	transaction, err := s.service.Deposit(ctx, walletID, amount, "")
+
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), transactionID, transaction.ID)
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestDeposit_IdempotencyKeyConflict() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	key := "retry-key-2"
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO idempotency_keys (key, request_hash) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`)).
+		WithArgs(key, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT request_hash, transaction_id FROM idempotency_keys WHERE key = $1`)).
+		WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "transaction_id"}).
+			AddRow(requestHash(walletID, "DEPOSIT", "5", ""), "6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	s.mock.ExpectRollback()
+
+	ctx := WithIdempotencyKey(context.Background(), key)
+	_, err := s.service.Deposit(ctx, walletID, decimal.NewFromInt(100), "")
+
+	assert.True(s.T(), errors.Is(err, ErrIdempotencyKeyConflict))
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestDeposit_IdempotencyKeyCurrencyConflict() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	key := "retry-key-3"
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO idempotency_keys (key, request_hash) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`)).
+		WithArgs(key, requestHash(walletID, "DEPOSIT", "100", "RUB")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT request_hash, transaction_id FROM idempotency_keys WHERE key = $1`)).
+		WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"request_hash", "transaction_id"}).
+			AddRow(requestHash(walletID, "DEPOSIT", "100", "USD"), "6ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+	s.mock.ExpectRollback()
+
+	ctx := WithIdempotencyKey(context.Background(), key)
+	_, err := s.service.Deposit(ctx, walletID, decimal.NewFromInt(100), "RUB")
+
+	assert.True(s.T(), errors.Is(err, ErrIdempotencyKeyConflict))
 	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
 }
 
@@ -95,25 +305,79 @@ func (s *WalletServiceSuite) TestDeposit_RetryOnSerializationError() {
 	s.mock.ExpectBegin().WillReturnError(&pgconn.PgError{Code: "40001"})
 
 	s.mock.ExpectBegin()
-	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
 		WithArgs(walletID).
-		WillReturnError(sql.ErrNoRows)
-	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO wallet_db (wallet_id, balance) VALUES ($1, $2)`)).
-		WithArgs(walletID, amount).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(decimal.Zero, decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE wallet_db SET balance = $1, updated_at = NOW() WHERE wallet_id = $2`)).
+		WithArgs(amount, walletID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO wallet_transactions (id, wallet_id, amount, operation_type, balance_after, counterparty_wallet_id, reversal_of) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`)).
+		WithArgs(sqlmock.AnyArg(), walletID, amount, "DEPOSIT", amount, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_accounts`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO ledger_postings`)).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_entries`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
 	s.mock.ExpectCommit()
 
-	err := s.service.Deposit(context.Background(), walletID, amount)
+	_, err := s.service.Deposit(context.Background(), walletID, amount, "")
 
 	assert.NoError(s.T(), err)
 	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
 }
 
+func (s *WalletServiceSuite) TestDeposit_RetriesExhausted() {
+	for i := 0; i < maxRetries; i++ {
+		s.mock.ExpectBegin().WillReturnError(&pgconn.PgError{Code: "40001"})
+	}
+
+	_, err := s.service.Deposit(context.Background(), "550e8400-e29b-41d4-a716-446655440000", decimal.NewFromInt(100), "")
+
+	assert.True(s.T(), errors.Is(err, ErrRetriesExhausted))
+	assert.Equal(s.T(), "RETRIES_EXHAUSTED", ErrorCode(err))
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestDeposit_RetrySpans() {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	for i := 0; i < maxRetries; i++ {
+		s.mock.ExpectBegin().WillReturnError(&pgconn.PgError{Code: "40001"})
+	}
+
+	_, err := s.service.Deposit(context.Background(), "550e8400-e29b-41d4-a716-446655440000", decimal.NewFromInt(100), "")
+	require.Error(s.T(), err)
+
+	var attempts int
+	for _, span := range recorder.Ended() {
+		attrs := attribute.NewSet(span.Attributes()...)
+		switch span.Name() {
+		case "wallet.tx.attempt":
+			attempts++
+			state, _ := attrs.Value(semconv.DBResponseStatusCodeKey)
+			stage, _ := attrs.Value("wallet.tx.stage")
+			assert.Equal(s.T(), "40001", state.AsString())
+			assert.Equal(s.T(), "begin", stage.AsString())
+		case "wallet.executeWithRetry":
+			assert.Equal(s.T(), codes.Error, span.Status().Code)
+			total, _ := attrs.Value("wallet.tx.attempts")
+			assert.Equal(s.T(), int64(maxRetries), total.AsInt64())
+		}
+	}
+	assert.Equal(s.T(), maxRetries, attempts)
+}
+
 func (s *WalletServiceSuite) TestDeposit_ContextCanceled() {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	err := s.service.Deposit(ctx, "550e8400-e29b-41d4-a716-446655440000", decimal.NewFromInt(100))
+	_, err := s.service.Deposit(ctx, "550e8400-e29b-41d4-a716-446655440000", decimal.NewFromInt(100), "")
 
 	assert.Error(s.T(), err)
 	assert.True(s.T(), errors.Is(err, context.Canceled))
@@ -122,18 +386,154 @@ func (s *WalletServiceSuite) TestDeposit_ContextCanceled() {
 func (s *WalletServiceSuite) TestGetBalance_NotFound() {
 	walletID := "550e8400-e29b-41d4-a716-446655440000"
 
-	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT wallet_id, balance, created_at, updated_at FROM wallet_db WHERE wallet_id = $1`)).
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT wallet_id, balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1`)).
 		WithArgs(walletID).
 		WillReturnError(sql.ErrNoRows)
 
 	_, err := s.service.GetBalance(context.Background(), walletID)
 
 	assert.Error(s.T(), err)
-	assert.True(s.T(), errors.Is(err, sql.ErrNoRows))
+	assert.True(s.T(), errors.Is(err, ErrWalletNotFound))
+	assert.Equal(s.T(), "WALLET_NOT_FOUND", ErrorCode(err))
+}
+
+func (s *WalletServiceSuite) TestDepositWithdraw_NonPositiveAmount() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+
+	for _, amount := range []decimal.Decimal{decimal.Zero, decimal.NewFromInt(-10)} {
+		_, err := s.service.Deposit(context.Background(), walletID, amount, "")
+		assert.ErrorIs(s.T(), err, ErrInvalidAmount, "deposit %s", amount)
+
+		_, err = s.service.Withdraw(context.Background(), walletID, amount, "")
+		assert.ErrorIs(s.T(), err, ErrInvalidAmount, "withdraw %s", amount)
+	}
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestGetTransactions() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+	createdAt := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "wallet_id", "amount", "operation_type", "balance_after", "counterparty_wallet_id", "reversal_of", "reversed_amount", "created_at"}).
+		AddRow("6ba7b810-9dad-11d1-80b4-00c04fd430c8", walletID, decimal.NewFromInt(-30), "WITHDRAW", decimal.NewFromInt(70), nil, nil, decimal.Zero, createdAt).
+		AddRow("6ba7b811-9dad-11d1-80b4-00c04fd430c8", walletID, decimal.NewFromInt(100), "DEPOSIT", decimal.NewFromInt(100), nil, nil, decimal.Zero, createdAt)
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT t.id, t.wallet_id, t.amount, t.operation_type, t.balance_after, t.counterparty_wallet_id, t.reversal_of, (SELECT COALESCE(SUM(ABS(r.amount)), 0) FROM wallet_transactions r WHERE r.reversal_of = t.id), t.created_at FROM wallet_transactions t WHERE t.wallet_id = $1 ORDER BY t.created_at DESC, t.id LIMIT $2 OFFSET $3`)).
+		WithArgs(walletID, 50, 0).
+		WillReturnRows(rows)
+
+	transactions, err := s.service.GetTransactions(context.Background(), walletID, 50, 0)
+
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), transactions, 2)
+	assert.Equal(s.T(), "WITHDRAW", transactions[0].OperationType)
+	assert.True(s.T(), transactions[0].BalanceAfter.Equal(decimal.NewFromInt(70)))
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestTransfer_LocksWalletsInOrder() {
+	fromWalletID := "c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b"
+	toWalletID := "550e8400-e29b-41d4-a716-446655440000"
+	amount := decimal.NewFromInt(40)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(toWalletID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(decimal.NewFromInt(10), decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(fromWalletID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(decimal.NewFromInt(100), decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE wallet_db SET balance = $1, updated_at = NOW() WHERE wallet_id = $2`)).
+		WithArgs(decimal.NewFromInt(60), fromWalletID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec(regexp.QuoteMeta(`UPDATE wallet_db SET balance = $1, updated_at = NOW() WHERE wallet_id = $2`)).
+		WithArgs(decimal.NewFromInt(50), toWalletID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO wallet_transactions`)).
+		WithArgs(sqlmock.AnyArg(), fromWalletID, amount.Neg(), "TRANSFER", decimal.NewFromInt(60), toWalletID, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO wallet_transactions`)).
+		WithArgs(sqlmock.AnyArg(), toWalletID, amount, "TRANSFER", decimal.NewFromInt(50), fromWalletID, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_accounts`)).
+		WithArgs(fromWalletID, "wallet", "RUB", fromWalletID, toWalletID, "wallet", "RUB", toWalletID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO ledger_postings`)).
+		WithArgs(sqlmock.AnyArg(), "TRANSFER").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	s.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO ledger_entries`)).
+		WithArgs(sqlmock.AnyArg(), fromWalletID, "RUB", amount.Neg(), sqlmock.AnyArg(), toWalletID, "RUB", amount, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	s.mock.ExpectCommit()
+
+	transaction, err := s.service.Transfer(context.Background(), fromWalletID, toWalletID, amount, "")
+
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), fromWalletID, transaction.WalletID)
+	assert.Equal(s.T(), toWalletID, transaction.CounterpartyWalletID)
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+func (s *WalletServiceSuite) TestTransfer_InsufficientFunds() {
+	fromWalletID := "550e8400-e29b-41d4-a716-446655440000"
+	toWalletID := "c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b"
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(fromWalletID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(decimal.NewFromInt(10), decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db WHERE wallet_id = $1 FOR UPDATE`)).
+		WithArgs(toWalletID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow(decimal.NewFromInt(0), decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+	s.mock.ExpectRollback()
+
+	_, err := s.service.Transfer(context.Background(), fromWalletID, toWalletID, decimal.NewFromInt(40), "")
+
+	assert.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "insufficient funds")
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
 }
 
-func (s *WalletServiceSuite) TestDeposit_ZeroAmount() {
-	err := s.service.Deposit(context.Background(), "550e8400-e29b-41d4-a716-446655440000", decimal.Zero)
+func (s *WalletServiceSuite) TestTransfer_SameWallet() {
+	walletID := "550e8400-e29b-41d4-a716-446655440000"
+
+	_, err := s.service.Transfer(context.Background(), walletID, walletID, decimal.NewFromInt(1), "")
+
+	assert.Error(s.T(), err)
+}
+
+func (s *WalletServiceSuite) TestListWallets_Filters() {
+	minBalance := decimal.NewFromInt(100)
+	createdFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastID := "550e8400-e29b-41d4-a716-446655440000"
+	cursor := encodeWalletCursor(model.Wallet{WalletID: lastID, Balance: decimal.NewFromInt(500)}, "balance", true)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT wallet_id, balance, held_balance, currency, status, owner_id, metadata, labels, created_at, updated_at FROM wallet_db `+
+		`WHERE status = $1 AND balance >= $2 AND created_at >= $3 AND (balance, wallet_id) < ($4, $5) ORDER BY balance DESC, wallet_id DESC LIMIT $6`)).
+		WithArgs("active", minBalance, createdFrom, decimal.NewFromInt(500), lastID, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"wallet_id", "balance", "held_balance", "currency", "status", "owner_id", "metadata", "labels", "created_at", "updated_at"}).
+			AddRow("c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b", decimal.NewFromInt(300), decimal.Zero, "RUB", "active", "user-42", []byte(`{"tier":"gold"}`), []byte(`["vip"]`), time.Now(), time.Now()).
+			AddRow("7b4f2e2a-1c1d-4b8e-9f0a-2d3c4b5a6978", decimal.NewFromInt(200), decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()).
+			AddRow("0d9e8f7a-6b5c-4d3e-2f1a-0b9c8d7e6f5a", decimal.NewFromInt(100), decimal.Zero, "RUB", "active", nil, []byte("{}"), []byte("[]"), time.Now(), time.Now()))
+
+	page, err := s.service.ListWallets(context.Background(), WalletQuery{
+		Status:      "active",
+		MinBalance:  &minBalance,
+		CreatedFrom: createdFrom,
+		SortBy:      "balance",
+		Desc:        true,
+		Cursor:      cursor,
+		Limit:       2,
+	})
 
 	assert.NoError(s.T(), err)
+	assert.Len(s.T(), page.Wallets, 2)
+	assert.Equal(s.T(), "user-42", page.Wallets[0].OwnerID)
+	assert.Equal(s.T(), []string{"vip"}, page.Wallets[0].Labels)
+	assert.Nil(s.T(), page.Wallets[1].Metadata)
+	assert.NotEmpty(s.T(), page.NextCursor)
+	assert.NoError(s.T(), s.mock.ExpectationsWereMet())
 }
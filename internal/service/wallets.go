@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/sunriseex/test_wallet/internal/currency"
+	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
+	"github.com/sunriseex/test_wallet/internal/repository"
+)
+
+const (
+	maxOwnerIDLength = 255
+	maxLabels        = 20
+	maxLabelLength   = 64
+	maxMetadataSize  = 4096
+	// maxOwnerWallets ограничивает выдачу ListWalletsByOwner.
+	maxOwnerWallets = 100
+)
+
+// CreateWalletParams — параметры нового кошелька. Пустой WalletID означает,
+// что ID сгенерирует сервис, пустой Currency — currency.Default.
+type CreateWalletParams struct {
+	WalletID string
+	Currency string
+	OwnerID  string
+	Metadata map[string]any
+	Labels   []string
+}
+
+// UpdateWalletParams — изменяемые поля кошелька. Nil означает «не менять»;
+// Metadata и Labels заменяются целиком, пустые значения их очищают.
+type UpdateWalletParams struct {
+	Status   *string
+	OwnerID  *string
+	Metadata map[string]any
+	Labels   []string
+}
+
+// CreateWallet создает активный кошелек с нулевым балансом.
+func (s *WalletServiceImpl) CreateWallet(ctx context.Context, params CreateWalletParams) (model.Wallet, error) {
+	walletID := params.WalletID
+	if walletID == "" {
+		walletID = uuid.NewString()
+	}
+	id, err := uuid.Parse(walletID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.Wallet{}, ErrInvalidWalletID
+	}
+	walletID = id.String()
+	ctx = logger.WithWalletID(ctx, walletID)
+
+	currencyCode, err := normalizeCurrency(params.Currency)
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	if currencyCode == "" {
+		currencyCode = currency.Default
+	}
+
+	wallet := model.Wallet{
+		WalletID: walletID,
+		Balance:  decimal.Zero,
+		Currency: currencyCode,
+		Status:   model.WalletStatusActive,
+	}
+	if err := applyWalletDetails(&wallet, &params.OwnerID, params.Metadata, params.Labels); err != nil {
+		return model.Wallet{}, err
+	}
+
+	var result model.Wallet
+	err = s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		created, err := tx.InsertWallet(ctx, wallet)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrWalletExists
+		}
+		if err != nil {
+			return err
+		}
+		result = created
+		return nil
+	})
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	logger.FromContext(ctx).Infof("Создан кошелек: wallet_id=%s, currency=%s, owner_id=%s", walletID, currencyCode, wallet.OwnerID)
+	return result, nil
+}
+
+// UpdateWallet меняет статус, владельца, метаданные и метки кошелька.
+// Закрыть можно только кошелек с нулевым балансом, закрытый кошелек больше
+// не меняется.
+func (s *WalletServiceImpl) UpdateWallet(ctx context.Context, walletID string, params UpdateWalletParams) (model.Wallet, error) {
+	ctx = logger.WithWalletID(ctx, walletID)
+	if _, err := uuid.Parse(walletID); err != nil {
+		logger.FromContext(ctx).Errorf("Неверный формат UUID: %s", walletID)
+		return model.Wallet{}, ErrInvalidWalletID
+	}
+	if params.Status != nil {
+		switch *params.Status {
+		case model.WalletStatusActive, model.WalletStatusFrozen, model.WalletStatusClosed:
+		default:
+			return model.Wallet{}, ErrInvalidWalletStatus
+		}
+	}
+
+	var result model.Wallet
+	err := s.executeWithRetry(ctx, func(ctx context.Context, tx repository.WalletTx) error {
+		wallet, err := tx.LockWallet(ctx, walletID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrWalletNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if wallet.Status == model.WalletStatusClosed {
+			return ErrWalletClosed
+		}
+
+		updated := wallet
+		if params.Status != nil {
+			if *params.Status == model.WalletStatusClosed && !wallet.Balance.IsZero() {
+				return ErrWalletNotEmpty
+			}
+			updated.Status = *params.Status
+		}
+		if err := applyWalletDetails(&updated, params.OwnerID, params.Metadata, params.Labels); err != nil {
+			return err
+		}
+		if err := tx.UpdateWallet(ctx, updated); err != nil {
+			return err
+		}
+		result = updated
+		return nil
+	})
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	logger.FromContext(ctx).Infof("Кошелек изменен: wallet_id=%s, status=%s, owner_id=%s", walletID, result.Status, result.OwnerID)
+	return result, nil
+}
+
+// ListWalletsByOwner возвращает кошельки владельца в порядке создания.
+func (s *WalletServiceImpl) ListWalletsByOwner(ctx context.Context, ownerID string) ([]model.Wallet, error) {
+	if ownerID == "" || len(ownerID) > maxOwnerIDLength {
+		return nil, ErrInvalidOwnerID
+	}
+	wallets, err := s.repo.ListWallets(ctx, repository.WalletFilter{
+		OwnerID: ownerID,
+		SortBy:  repository.WalletSortCreatedAt,
+		Limit:   maxOwnerWallets,
+	})
+	if err != nil {
+		logger.FromContext(ctx).WithError(err).Errorf("Ошибка получения кошельков владельца: owner_id=%s", ownerID)
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// applyWalletDetails проверяет и записывает в wallet заданные поля. Метки
+// обрезаются по краям пробелов, повторы отбрасываются.
+func applyWalletDetails(wallet *model.Wallet, ownerID *string, metadata map[string]any, labels []string) error {
+	if ownerID != nil {
+		if len(*ownerID) > maxOwnerIDLength {
+			return ErrInvalidOwnerID
+		}
+		wallet.OwnerID = *ownerID
+	}
+
+	if metadata != nil {
+		encoded, err := json.Marshal(metadata)
+		if err != nil || len(encoded) > maxMetadataSize {
+			return ErrInvalidMetadata
+		}
+		wallet.Metadata = metadata
+		if len(metadata) == 0 {
+			wallet.Metadata = nil
+		}
+	}
+
+	if labels != nil {
+		if len(labels) > maxLabels {
+			return ErrInvalidLabels
+		}
+		seen := make(map[string]bool, len(labels))
+		normalized := make([]string, 0, len(labels))
+		for _, label := range labels {
+			label = strings.TrimSpace(label)
+			if label == "" || len(label) > maxLabelLength {
+				return ErrInvalidLabels
+			}
+			if !seen[label] {
+				seen[label] = true
+				normalized = append(normalized, label)
+			}
+		}
+		wallet.Labels = normalized
+		if len(normalized) == 0 {
+			wallet.Labels = nil
+		}
+	}
+	return nil
+}
+
+// checkStatus проверяет, что кошелек принимает операцию: закрытый — никакую,
+// замороженный — только зачисления.
+func checkStatus(wallet model.Wallet, debit bool) error {
+	switch {
+	case wallet.Status == model.WalletStatusClosed:
+		return ErrWalletClosed
+	case debit && wallet.Status == model.WalletStatusFrozen:
+		return ErrWalletFrozen
+	}
+	return nil
+}
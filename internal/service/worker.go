@@ -2,16 +2,22 @@ package service
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/shopspring/decimal"
 	"github.com/sunriseex/test_wallet/internal/logger"
+	"github.com/sunriseex/test_wallet/internal/model"
 )
 
+// pendingBatchSize — сколько незавершенных операций читать за раз при
+// восстановлении очереди.
+const pendingBatchSize = 500
+
 type Job struct {
-	WalletID string
-	Amount   decimal.Decimal
-	Ctx      context.Context
+	Operation model.Operation
+	Ctx       context.Context
 }
 
 type WorkerPool struct {
@@ -19,6 +25,9 @@ type WorkerPool struct {
 	wg        sync.WaitGroup
 	svc       *WalletServiceImpl
 	queueSize int
+	mu        sync.RWMutex
+	closed    atomic.Bool
+	stop      chan struct{}
 }
 
 func NewWorkerPool(svc *WalletServiceImpl, workers, queueSize int) *WorkerPool {
@@ -26,6 +35,7 @@ func NewWorkerPool(svc *WalletServiceImpl, workers, queueSize int) *WorkerPool {
 		jobs:      make(chan Job, queueSize),
 		svc:       svc,
 		queueSize: queueSize,
+		stop:      make(chan struct{}),
 	}
 	for i := 0; i < workers; i++ {
 		wp.wg.Add(1)
@@ -39,14 +49,22 @@ func NewWorkerPool(svc *WalletServiceImpl, workers, queueSize int) *WorkerPool {
 func (wp *WorkerPool) worker() {
 	defer wp.wg.Done()
 	for job := range wp.jobs {
-		if err := wp.svc.updateBalance(job.Ctx, job.WalletID, job.Amount); err != nil {
-			logger.Log.Errorf("Ошибка обновления баланса для WalletID=%s: %v", job.WalletID, err)
+		ctx := job.Ctx
+		if ctx == nil {
+			ctx = context.Background()
 		}
-
+		op := wp.svc.executeOperation(ctx, job.Operation)
+		logger.FromContext(ctx).WithField(logger.FieldWalletID, op.WalletID).Infof("Операция обработана: id=%s, status=%s", op.ID, op.Status)
 	}
 }
 
 func (wp *WorkerPool) AddJob(job Job) bool {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	if wp.closed.Load() {
+		return false
+	}
+
 	select {
 	case wp.jobs <- job:
 		return true
@@ -55,7 +73,74 @@ func (wp *WorkerPool) AddJob(job Job) bool {
 	}
 }
 
+// RequeuePending ставит в очередь операции, оставшиеся в статусе pending после
+// остановки или падения процесса. В отличие от AddJob ждет места в очереди.
+// Операция выполняется под своим ключом идемпотентности, поэтому повторный
+// запуск уже выполненной операции лишь вернет ее результат.
+func (wp *WorkerPool) RequeuePending(ctx context.Context) (int, error) {
+	createdBefore := time.Now()
+	queued := 0
+	afterID := ""
+	for {
+		ops, err := wp.svc.repo.ListPendingOperations(ctx, createdBefore, afterID, pendingBatchSize)
+		if err != nil {
+			return queued, err
+		}
+		for _, op := range ops {
+			if !wp.enqueue(ctx, Job{Operation: op, Ctx: ctx}) {
+				return queued, nil
+			}
+			queued++
+		}
+		if len(ops) < pendingBatchSize {
+			return queued, nil
+		}
+		afterID = ops[len(ops)-1].ID
+	}
+}
+
+// enqueue ставит задание в очередь, дожидаясь места. Возвращает false, если
+// пул остановлен или ctx отменен.
+func (wp *WorkerPool) enqueue(ctx context.Context, job Job) bool {
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	if wp.closed.Load() {
+		return false
+	}
+
+	select {
+	case wp.jobs <- job:
+		return true
+	case <-wp.stop:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (wp *WorkerPool) QueueDepth() int {
+	return len(wp.jobs)
+}
+
+// Ready сообщает, принимает ли пул новые задания.
+func (wp *WorkerPool) Ready(ctx context.Context) error {
+	if wp.closed.Load() {
+		return errors.New("worker pool is shut down")
+	}
+	if len(wp.jobs) >= wp.queueSize {
+		return errors.New("worker pool queue is full")
+	}
+	return nil
+}
+
 func (wp *WorkerPool) Shutdown() {
+	if wp.closed.Swap(true) {
+		return
+	}
+	// Будим enqueue, ждущий места в очереди, чтобы он отпустил mu.
+	close(wp.stop)
+	wp.mu.Lock()
 	close(wp.jobs)
+	wp.mu.Unlock()
 	wp.wg.Wait()
 }
@@ -0,0 +1,110 @@
+// Package statement кодирует выписки по кошельку в CSV и JSON потоком:
+// операции пишутся по мере поступления и не накапливаются в памяти.
+package statement
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/sunriseex/test_wallet/internal/model"
+)
+
+// Строки CSV с балансами на начало и конец периода.
+const (
+	RowOpeningBalance = "OPENING_BALANCE"
+	RowClosingBalance = "CLOSING_BALANCE"
+)
+
+var csvHeader = []string{"created_at", "transaction_id", "operation_type", "amount", "balance_after", "counterparty_wallet_id", "reversal_of"}
+
+// CSVWriter пишет выписку в CSV: заголовок, строку OPENING_BALANCE, операции
+// и строку CLOSING_BALANCE. Баланс в служебных строках — в колонке balance_after.
+type CSVWriter struct {
+	w  *csv.Writer
+	to time.Time
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) Begin(header model.StatementHeader) error {
+	c.to = header.To
+	if err := c.w.Write(csvHeader); err != nil {
+		return err
+	}
+	return c.w.Write([]string{formatTime(header.From), "", RowOpeningBalance, "", header.OpeningBalance.String(), "", ""})
+}
+
+func (c *CSVWriter) Operation(t model.Transaction) error {
+	return c.w.Write([]string{
+		formatTime(t.CreatedAt),
+		t.ID,
+		t.OperationType,
+		t.Amount.String(),
+		t.BalanceAfter.String(),
+		t.CounterpartyWalletID,
+		t.ReversalOf,
+	})
+}
+
+func (c *CSVWriter) End(summary model.StatementSummary) error {
+	if err := c.w.Write([]string{formatTime(c.to), "", RowClosingBalance, "", summary.ClosingBalance.String(), "", ""}); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// JSONWriter пишет выписку одним JSON-объектом: поля заголовка, массив
+// operations и поля итога.
+type JSONWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: bufio.NewWriter(w)}
+}
+
+func (j *JSONWriter) Begin(header model.StatementHeader) error {
+	head, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	// Объект заголовка остается открытым: дальше идут операции и итог.
+	j.w.Write(head[:len(head)-1])
+	_, err = j.w.WriteString(`,"operations":[`)
+	return err
+}
+
+func (j *JSONWriter) Operation(t model.Transaction) error {
+	op, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		j.w.WriteByte(',')
+	}
+	j.count++
+	_, err = j.w.Write(op)
+	return err
+}
+
+func (j *JSONWriter) End(summary model.StatementSummary) error {
+	tail, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	j.w.WriteString("],")
+	j.w.Write(tail[1:])
+	j.w.WriteByte('\n')
+	return j.w.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -20,9 +20,19 @@ export const options = {
   },
 };
 
+// 429 — ожидаемый ответ лимитера при нагрузке на один кошелек, а не ошибка
+http.setResponseCallback(http.expectedStatuses(200, 429));
+
 const WALLET_ID = 'c9c5c5e0-7b3a-4e3a-9b3d-3d9b2e3d3d9b';
 const BASE_URL = 'http://localhost:8080/api/v1';
 
+// Кошелек создается один раз перед нагрузкой (201 или 409, если уже есть)
+export function setup() {
+  http.post(`${BASE_URL}/wallets`, JSON.stringify({ walletId: WALLET_ID }), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+}
+
 export default function () {
   // Тестируем 50% депозитов и 50% проверок баланса
   if (Math.random() < 0.5) {
@@ -37,12 +47,12 @@ export default function () {
     });
 
     check(res, {
-      'POST status 200': (r) => r.status === 200,
+      'POST status 200 or 429': (r) => r.status === 200 || r.status === 429,
     });
   } else {
     const res = http.get(`${BASE_URL}/wallets/${WALLET_ID}`);
     check(res, {
-      'GET status 200': (r) => r.status === 200,
+      'GET status 200 or 429': (r) => r.status === 200 || r.status === 429,
     });
   }
 }
\ No newline at end of file